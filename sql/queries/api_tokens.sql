@@ -0,0 +1,26 @@
+-- name: CreateApiToken :one
+INSERT INTO api_tokens (id, user_id, name, token_hash, scope, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetApiTokensForUser :many
+SELECT id, name, scope, created_at, last_used_at FROM api_tokens
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: UseApiToken :one
+WITH used_token AS (
+  UPDATE api_tokens SET last_used_at = sqlc.arg(now)
+  WHERE token_hash = sqlc.arg(token_hash)
+  RETURNING user_id, scope
+)
+SELECT users.*, used_token.scope FROM users
+INNER JOIN used_token ON users.id = used_token.user_id;
+
+-- name: DeleteApiToken :execrows
+DELETE FROM api_tokens
+WHERE user_id = $1 AND name = $2;
+
+-- name: DeleteApiTokensForUser :exec
+DELETE FROM api_tokens
+WHERE user_id = $1;
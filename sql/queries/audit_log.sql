@@ -0,0 +1,8 @@
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (id, user_id, user_name, action, details, created_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetAuditEntries :many
+SELECT * FROM audit_log
+ORDER BY created_at DESC
+LIMIT $1;
@@ -0,0 +1,46 @@
+-- name: SetUserPassword :exec
+INSERT INTO user_passwords (user_id, password_hash, created_at, updated_at)
+VALUES ($1, $2, $3, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET password_hash = excluded.password_hash, updated_at = excluded.updated_at;
+
+-- name: GetUserPasswordHash :one
+SELECT password_hash FROM user_passwords
+WHERE user_id = $1;
+
+-- name: CreateSession :one
+INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetUserBySession :one
+SELECT users.* FROM users
+INNER JOIN sessions ON users.id = sessions.user_id
+WHERE sessions.token_hash = sqlc.arg(token_hash) AND sessions.expires_at > sqlc.arg(now);
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM sessions
+WHERE expires_at <= $1;
+
+-- name: DeleteSessionsForUser :exec
+DELETE FROM sessions
+WHERE user_id = $1;
+
+-- name: DeleteUserPassword :exec
+DELETE FROM user_passwords
+WHERE user_id = $1;
+
+-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (user_id, token_hash, created_at, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE
+SET token_hash = excluded.token_hash, created_at = excluded.created_at, expires_at = excluded.expires_at;
+
+-- name: UsePasswordReset :execrows
+-- Tokens are deleted when they are used, so they work only once
+DELETE FROM password_resets
+WHERE user_id = sqlc.arg(user_id) AND token_hash = sqlc.arg(token_hash) AND expires_at > sqlc.arg(now);
@@ -17,7 +17,7 @@ INNER JOIN users ON inserted_feed_follow.user_id = users.id
 INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, feeds.name as feed_name, users.name as user_name
+SELECT feed_follows.*, feeds.name as feed_name, feeds.url as feed_url, users.name as user_name
 FROM feed_follows
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
@@ -32,3 +32,8 @@ USING feeds
   feed_follows.user_id = $1 AND
   feeds.url = sqlc.arg(feed_url)
 RETURNING feed_follows.*;
+
+-- name: SetFeedFollowCategory :exec
+UPDATE feed_follows
+SET category = $3, updated_at = $4
+WHERE user_id = $1 AND feed_id = $2;
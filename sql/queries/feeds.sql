@@ -19,13 +19,75 @@ SELECT feeds.url, feeds.name, users.name as user_name from feeds
 INNER JOIN users ON feeds.user_id = users.id;
 
 -- name: MarkFeedFetched :one
+-- The next fetch is scheduled with the clock of the database, like leases
 UPDATE feeds
-SET last_fetched_at = $2, updated_at = $2
-WHERE id = $1
+SET last_fetched_at = sqlc.arg(last_fetched_at), updated_at = sqlc.arg(last_fetched_at),
+    etag = sqlc.arg(etag), last_modified = sqlc.arg(last_modified),
+    next_fetch_at = now() + sqlc.arg(next_fetch_seconds)::integer * interval '1 second',
+    consecutive_failures = 0, last_success_at = sqlc.arg(last_fetched_at), claimed_by = NULL, lease_expires_at = NULL
+WHERE id = sqlc.arg(id) AND (claimed_by IS NULL OR claimed_by = sqlc.arg(claimed_by))
 RETURNING *;
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
+-- name: ClaimFeedsToFetch :many
+-- Leases are compared with the clock of the database, so aggregators with
+-- skewed clocks agree on when a lease expired
+UPDATE feeds
+SET claimed_by = sqlc.arg(claimed_by), lease_expires_at = now() + sqlc.arg(lease_seconds)::integer * interval '1 second'
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE disabled_at IS NULL AND (next_fetch_at IS NULL OR next_fetch_at <= now())
+      AND (lease_expires_at IS NULL OR lease_expires_at <= now())
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE disabled_at IS NULL AND (next_fetch_at IS NULL OR next_fetch_at <= now())
+  AND (lease_expires_at IS NULL OR lease_expires_at <= now());
+
+-- name: ReleaseFeedLeases :execrows
+UPDATE feeds
+SET claimed_by = NULL, lease_expires_at = NULL
+WHERE claimed_by = $1;
+
+-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET claimed_by = NULL, lease_expires_at = NULL
+WHERE id = $1 AND claimed_by = $2;
+
+-- name: SetFeedRefreshInterval :one
+-- The interval applies to every follower, so only the user who added the
+-- feed and admins may change it
+UPDATE feeds
+SET refresh_interval_minutes = $3, next_fetch_at = $4, updated_at = $4
+WHERE url = $1 AND (user_id = $2 OR EXISTS (
+    SELECT 1 FROM users WHERE users.id = $2 AND users.is_admin
+))
+RETURNING *;
+
+-- name: MarkFeedFailed :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1, last_error = sqlc.arg(last_error), last_error_at = sqlc.arg(last_error_at),
+    next_fetch_at = now() + sqlc.arg(backoff_seconds)::integer * interval '1 second',
+    disabled_at = sqlc.arg(disabled_at), updated_at = sqlc.arg(last_error_at), claimed_by = NULL, lease_expires_at = NULL
+WHERE id = sqlc.arg(id) AND (claimed_by IS NULL OR claimed_by = sqlc.arg(claimed_by))
+RETURNING *;
 
+-- name: ListFeedHealth :many
+SELECT url, name, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at
+FROM feeds
+WHERE consecutive_failures > 0 OR disabled_at IS NOT NULL
+ORDER BY disabled_at ASC NULLS LAST, consecutive_failures DESC;
+
+-- name: EnableFeed :one
+UPDATE feeds
+SET disabled_at = NULL, consecutive_failures = 0, next_fetch_at = NULL, updated_at = $2
+WHERE url = $1
+RETURNING *;
+
+-- name: GetFeedByShortID :one
+SELECT * FROM feeds
+WHERE short_id = $1;
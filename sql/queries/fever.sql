@@ -0,0 +1,61 @@
+-- name: SetFeverApiKey :exec
+INSERT INTO fever_accounts (user_id, api_key, created_at, updated_at)
+VALUES ($1, $2, $3, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET api_key = excluded.api_key, updated_at = excluded.updated_at;
+
+-- name: DeleteFeverAccount :exec
+DELETE FROM fever_accounts
+WHERE user_id = $1;
+
+-- name: GetUserByFeverApiKey :one
+SELECT users.* FROM users
+INNER JOIN fever_accounts ON users.id = fever_accounts.user_id
+WHERE fever_accounts.api_key = $1;
+
+-- name: GetFeverFeeds :many
+SELECT feeds.short_id, feeds.name, feeds.url, feeds.last_fetched_at, feed_follows.category
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.short_id;
+
+-- name: GetFeverItems :many
+SELECT posts.short_id, feeds.short_id AS feed_short_id, posts.title, posts.url, posts.description,
+  posts.published_at, posts.created_at,
+  COALESCE(post_states.read, false)::boolean AS is_read,
+  (starred_posts.post_id IS NOT NULL)::boolean AS is_saved
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+LEFT JOIN starred_posts ON starred_posts.post_id = posts.id AND starred_posts.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(since_id)::bigint IS NULL OR posts.short_id > sqlc.narg(since_id)::bigint)
+  AND (sqlc.narg(max_id)::bigint IS NULL OR posts.short_id < sqlc.narg(max_id)::bigint)
+  AND (sqlc.narg(with_ids)::bigint[] IS NULL OR posts.short_id = ANY(sqlc.narg(with_ids)::bigint[]))
+-- Items before max_id are returned newest first, all others oldest first
+ORDER BY CASE WHEN sqlc.narg(max_id)::bigint IS NULL THEN posts.short_id ELSE -posts.short_id END
+LIMIT sqlc.arg(page_size);
+
+-- name: CountFeverItems :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1;
+
+-- name: GetFeverUnreadItemIds :many
+SELECT posts.short_id FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1 AND NOT EXISTS (
+  SELECT 1 FROM post_states
+  WHERE post_states.post_id = posts.id
+  AND post_states.user_id = feed_follows.user_id
+  AND post_states.read
+)
+ORDER BY posts.short_id;
+
+-- name: GetFeverSavedItemIds :many
+SELECT posts.short_id FROM starred_posts
+INNER JOIN posts ON starred_posts.post_id = posts.id
+WHERE starred_posts.user_id = $1
+ORDER BY posts.short_id;
@@ -0,0 +1,48 @@
+-- name: SetPostRead :one
+INSERT INTO post_states (user_id, post_id, read, read_at, created_at, updated_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $5
+)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read = excluded.read, read_at = excluded.read_at, updated_at = excluded.updated_at
+RETURNING *;
+
+-- name: MarkAllPostsRead :execrows
+-- Posts without publication date are compared by the time they were stored
+INSERT INTO post_states (user_id, post_id, read, read_at, created_at, updated_at)
+SELECT feed_follows.user_id, posts.id, true, sqlc.arg(read_at)::timestamp, sqlc.arg(read_at)::timestamp, sqlc.arg(read_at)::timestamp
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url)::text)
+  AND (sqlc.narg(published_before)::timestamptz IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(published_before)::timestamptz)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read = true, read_at = excluded.read_at, updated_at = excluded.updated_at
+WHERE post_states.read = false;
+
+-- name: CountUnreadPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1 AND NOT EXISTS (
+  SELECT 1 FROM post_states
+  WHERE post_states.post_id = posts.id
+  AND post_states.user_id = feed_follows.user_id
+  AND post_states.read
+);
+
+-- name: GetUnreadCountsForUser :many
+SELECT feeds.id, feeds.name, feeds.url, COUNT(posts.id) FILTER (WHERE post_states.read IS NOT TRUE) AS unread_count
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN posts ON posts.feed_id = feeds.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id, feeds.name, feeds.url
+ORDER BY feeds.name;
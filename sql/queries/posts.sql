@@ -20,3 +20,87 @@ WHERE feed_follows.user_id = $1
 ORDER BY published_at DESC
 LIMIT $2;
 
+
+-- name: GetRecentPublicationDates :many
+SELECT published_at FROM posts
+WHERE feed_id = $1 AND published_at IS NOT NULL
+ORDER BY published_at DESC
+LIMIT $2;
+
+-- name: GetPost :one
+SELECT * FROM posts
+WHERE id = $1 LIMIT 1;
+
+-- name: GetPostByUrl :one
+SELECT * FROM posts
+WHERE url = $1 LIMIT 1;
+
+-- name: GetPostForUser :one
+-- Only posts of followed feeds are visible
+SELECT posts.* FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE posts.id = $1 AND feed_follows.user_id = $2;
+
+-- name: SearchPostsForUser :many
+SELECT posts.id, posts.url, posts.title, posts.published_at, feeds.name AS feed_name,
+  ts_rank(posts.search_vector, websearch_to_tsquery('english', sqlc.arg(query)::text)) AS rank
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND posts.search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text)
+  AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url)::text)
+  AND (sqlc.narg(published_after)::timestamptz IS NULL OR posts.published_at >= sqlc.narg(published_after)::timestamptz)
+  AND (sqlc.narg(published_before)::timestamptz IS NULL OR posts.published_at < sqlc.narg(published_before)::timestamptz)
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT sqlc.arg(result_limit);
+
+-- name: BrowsePostsForUser :many
+SELECT posts.id, posts.url, posts.title, posts.created_at, posts.updated_at, posts.description, posts.published_at, posts.feed_id, feeds.name AS feed_name, feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url)::text)
+  AND (sqlc.narg(published_after)::timestamptz IS NULL OR posts.published_at >= sqlc.narg(published_after)::timestamptz)
+  AND (sqlc.narg(published_before)::timestamptz IS NULL OR posts.published_at < sqlc.narg(published_before)::timestamptz)
+  AND (NOT sqlc.arg(unread_only)::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id
+    AND post_states.user_id = feed_follows.user_id
+    AND post_states.read
+  ))
+  AND (sqlc.narg(after_id)::uuid IS NULL OR (COALESCE(posts.published_at, '-infinity'::timestamptz), posts.id) < (
+    SELECT COALESCE(cursor_post.published_at, '-infinity'::timestamptz), cursor_post.id
+    FROM posts AS cursor_post
+    WHERE cursor_post.id = sqlc.narg(after_id)::uuid
+  ))
+ORDER BY COALESCE(posts.published_at, '-infinity'::timestamptz) DESC, posts.id DESC
+LIMIT sqlc.arg(result_limit) OFFSET sqlc.arg(result_offset);
+
+-- name: BrowsePostsForUserOldestFirst :many
+SELECT posts.id, posts.url, posts.title, posts.created_at, posts.updated_at, posts.description, posts.published_at, posts.feed_id, feeds.name AS feed_name, feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url)::text)
+  AND (sqlc.narg(published_after)::timestamptz IS NULL OR posts.published_at >= sqlc.narg(published_after)::timestamptz)
+  AND (sqlc.narg(published_before)::timestamptz IS NULL OR posts.published_at < sqlc.narg(published_before)::timestamptz)
+  AND (NOT sqlc.arg(unread_only)::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id
+    AND post_states.user_id = feed_follows.user_id
+    AND post_states.read
+  ))
+  AND (sqlc.narg(after_id)::uuid IS NULL OR (COALESCE(posts.published_at, '-infinity'::timestamptz), posts.id) > (
+    SELECT COALESCE(cursor_post.published_at, '-infinity'::timestamptz), cursor_post.id
+    FROM posts AS cursor_post
+    WHERE cursor_post.id = sqlc.narg(after_id)::uuid
+  ))
+ORDER BY COALESCE(posts.published_at, '-infinity'::timestamptz) ASC, posts.id ASC
+LIMIT sqlc.arg(result_limit) OFFSET sqlc.arg(result_offset);
+
+-- name: GetPostByShortID :one
+SELECT * FROM posts
+WHERE short_id = $1;
@@ -0,0 +1,24 @@
+-- name: GetReaderItems :many
+SELECT posts.short_id, posts.title, posts.url, posts.description, posts.published_at, posts.created_at,
+  feeds.name AS feed_name, feeds.url AS feed_url, feed_follows.category,
+  COALESCE(post_states.read, false)::boolean AS is_read,
+  (starred_posts.post_id IS NOT NULL)::boolean AS is_starred
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+LEFT JOIN starred_posts ON starred_posts.post_id = posts.id AND starred_posts.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url)::text)
+  AND (sqlc.narg(category)::text IS NULL OR feed_follows.category = sqlc.narg(category)::text)
+  AND (sqlc.narg(is_read)::boolean IS NULL OR COALESCE(post_states.read, false) = sqlc.narg(is_read)::boolean)
+  AND (sqlc.narg(is_starred)::boolean IS NULL OR (starred_posts.post_id IS NOT NULL) = sqlc.narg(is_starred)::boolean)
+  AND (sqlc.narg(published_after)::timestamptz IS NULL OR posts.published_at >= sqlc.narg(published_after)::timestamptz)
+  AND (sqlc.narg(published_before)::timestamptz IS NULL OR posts.published_at < sqlc.narg(published_before)::timestamptz)
+  AND (sqlc.narg(with_ids)::bigint[] IS NULL OR posts.short_id = ANY(sqlc.narg(with_ids)::bigint[]))
+  AND (sqlc.narg(continue_after)::bigint IS NULL OR CASE
+    WHEN sqlc.arg(oldest_first)::boolean THEN posts.short_id > sqlc.narg(continue_after)::bigint
+    ELSE posts.short_id < sqlc.narg(continue_after)::bigint
+  END)
+ORDER BY CASE WHEN sqlc.arg(oldest_first)::boolean THEN posts.short_id ELSE -posts.short_id END
+LIMIT sqlc.arg(result_limit);
@@ -0,0 +1,25 @@
+-- name: StarPost :one
+INSERT INTO starred_posts (user_id, post_id, note, created_at, updated_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $4
+)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET note = excluded.note, updated_at = excluded.updated_at
+RETURNING *;
+
+-- name: UnstarPost :execrows
+DELETE FROM starred_posts
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetStarredPostsForUser :many
+SELECT posts.id, posts.url, posts.title, posts.created_at, posts.updated_at, posts.description, posts.published_at, posts.feed_id, starred_posts.note, starred_posts.created_at AS starred_at, feeds.name AS feed_name, feeds.url AS feed_url
+FROM starred_posts
+INNER JOIN posts ON starred_posts.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE starred_posts.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url)::text)
+ORDER BY starred_posts.created_at DESC;
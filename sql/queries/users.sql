@@ -1,10 +1,13 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+-- The first user registered at the CLI administers the instance. Users
+-- registered over the api are never admins.
+INSERT INTO users (id, created_at, updated_at, name, is_admin)
 VALUES (
-    $1,
-    $2,
-    $3,
-    $4
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(updated_at),
+    sqlc.arg(name),
+    sqlc.arg(admin_if_first)::boolean AND NOT EXISTS (SELECT 1 FROM users)
 )
 RETURNING *;
 
@@ -18,3 +21,15 @@ SELECT * FROM users;
 -- name: DeleteAllUsers :exec
 DELETE FROM users;
 
+-- name: SetUserAdmin :execrows
+UPDATE users SET is_admin = $2, updated_at = $3
+WHERE name = $1;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE is_admin;
+
+-- name: CountAdminsWithPassword :one
+SELECT COUNT(*) FROM users
+INNER JOIN user_passwords ON users.id = user_passwords.user_id
+WHERE users.is_admin;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts
+ALTER COLUMN published_at TYPE TIMESTAMPTZ
+USING published_at AT TIME ZONE 'UTC';
+
+-- +goose Down
+ALTER TABLE posts
+ALTER COLUMN published_at TYPE TIMESTAMP
+USING published_at AT TIME ZONE 'UTC';
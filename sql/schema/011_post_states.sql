@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE post_states (
+  user_id UUID NOT NULL,
+  CONSTRAINT fk_user_id
+  FOREIGN KEY(user_id)
+  REFERENCES users(id)
+  ON DELETE CASCADE,
+
+  post_id UUID NOT NULL,
+  CONSTRAINT fk_post_id
+  FOREIGN KEY(post_id)
+  REFERENCES posts(id)
+  ON DELETE CASCADE,
+
+  read BOOLEAN NOT NULL DEFAULT false,
+  read_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+
+  PRIMARY KEY(user_id, post_id)
+);
+
+-- Unread posts are found with an anti join against post_states, so the posts
+-- of the followed feeds have to be found quickly
+CREATE INDEX posts_feed_id_published_at_idx ON posts (feed_id, published_at DESC);
+
+-- +goose Down
+DROP INDEX posts_feed_id_published_at_idx;
+DROP TABLE post_states;
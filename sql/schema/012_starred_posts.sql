@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE starred_posts (
+  user_id UUID NOT NULL,
+  CONSTRAINT fk_user_id
+  FOREIGN KEY(user_id)
+  REFERENCES users(id)
+  ON DELETE CASCADE,
+
+  post_id UUID NOT NULL,
+  CONSTRAINT fk_post_id
+  FOREIGN KEY(post_id)
+  REFERENCES posts(id)
+  ON DELETE CASCADE,
+
+  note TEXT,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+
+  PRIMARY KEY(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE starred_posts;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Keyset pagination sorts posts without publication date as the oldest ones
+CREATE INDEX posts_browse_idx ON posts ((COALESCE(published_at, '-infinity'::timestamptz)), id);
+
+-- +goose Down
+DROP INDEX posts_browse_idx;
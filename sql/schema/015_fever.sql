@@ -0,0 +1,17 @@
+-- +goose Up
+-- Clients of the Fever api identify feeds and items by integers
+ALTER TABLE feeds ADD COLUMN short_id BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE;
+ALTER TABLE posts ADD COLUMN short_id BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE;
+
+CREATE TABLE fever_accounts (
+  user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  -- md5 of "username:password" as defined by the Fever api
+  api_key TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE fever_accounts;
+ALTER TABLE posts DROP COLUMN short_id;
+ALTER TABLE feeds DROP COLUMN short_id;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE user_passwords (
+  user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  -- bcrypt hash of the password
+  password_hash TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE sessions (
+  -- sha256 of the token, the token itself is only stored in the config of the user
+  token_hash TEXT PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE sessions;
+DROP TABLE user_passwords;
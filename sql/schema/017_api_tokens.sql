@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  -- sha256 of the token, the token is only shown once when it is created
+  token_hash TEXT NOT NULL UNIQUE,
+  scope TEXT NOT NULL CHECK (scope IN ('read', 'manage')),
+  created_at TIMESTAMP NOT NULL,
+  last_used_at TIMESTAMP,
+  UNIQUE (user_id, name)
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+-- The oldest user administers existing installations
+UPDATE users SET is_admin = true
+WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1);
+
+-- Entries outlive the users who created them, e.g. after 'reset', so the
+-- user is not a foreign key and the name is kept
+CREATE TABLE audit_log (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL,
+  user_name TEXT NOT NULL,
+  action TEXT NOT NULL,
+  details TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+
+-- +goose Down
+DROP TABLE audit_log;
+ALTER TABLE users DROP COLUMN is_admin;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Aggregators lease the feeds they fetch, so several instances divide the
+-- work. Feeds of crashed aggregators are claimed again once the lease expired.
+ALTER TABLE feeds
+ADD COLUMN claimed_by TEXT,
+ADD COLUMN lease_expires_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN claimed_by,
+DROP COLUMN lease_expires_at;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- One-time tokens, created by admins, with which users without password
+-- choose one at their next login
+CREATE TABLE password_resets (
+  user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  -- sha256 of the token, the token itself is only shown to the admin
+  token_hash TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE password_resets;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Aggregators on hosts in different time zones compare the schedule and the
+-- leases of the feeds, so they are stored with their time zone
+ALTER TABLE feeds
+ALTER COLUMN next_fetch_at TYPE TIMESTAMPTZ
+USING next_fetch_at AT TIME ZONE 'UTC',
+ALTER COLUMN lease_expires_at TYPE TIMESTAMPTZ
+USING lease_expires_at AT TIME ZONE 'UTC';
+
+-- +goose Down
+ALTER TABLE feeds
+ALTER COLUMN next_fetch_at TYPE TIMESTAMP
+USING next_fetch_at AT TIME ZONE 'UTC',
+ALTER COLUMN lease_expires_at TYPE TIMESTAMP
+USING lease_expires_at AT TIME ZONE 'UTC';
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/term"
+)
+
+const defaultAuditEntries = 20
+
+// middlewareAdmin restricts commands that affect every user to admins
+func middlewareAdmin(required accessScope, handler func(state *State, arguments []string, user database.User) error) func(*State, []string) error {
+	return middlewareScope(required, func(state *State, arguments []string, user database.User) error {
+		if !user.IsAdmin {
+			return permissionError(fmt.Sprintf("User '%s' is not an admin", user.Name))
+		}
+		return handler(state, arguments, user)
+	})
+}
+
+// confirmAction asks the user to type the word unless --yes was given.
+// Piped input is read like typed input, so scripts have to pass --yes.
+func confirmAction(yes bool, question string, word string) error {
+	if yes {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "%s Type '%s' to confirm: ", question, word)
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("Aborted, no confirmation was given. Pass --yes to skip it")
+	}
+	if strings.TrimSpace(line) != word {
+		return fmt.Errorf("Aborted, the confirmation did not match")
+	}
+	return nil
+}
+
+// recordAudit writes who ran an admin action. The action is aborted if the
+// record cannot be written.
+func recordAudit(ctx context.Context, state *State, user database.User, action string, details string) error {
+	if err := state.db.CreateAuditEntry(ctx, database.CreateAuditEntryParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		UserName:  user.Name,
+		Action:    action,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("Failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+func resetUsersCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("reset", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "skip the confirmation")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'reset' command only accepts flags")
+	}
+	if err := confirmAction(*yes, "This deletes all users with their feeds, follows and posts.", "reset"); err != nil {
+		return err
+	}
+
+	if err := recordAudit(context.Background(), state, user, "reset", "deleted all users"); err != nil {
+		return err
+	}
+	if err := state.db.DeleteAllUsers(context.Background()); err != nil {
+		return fmt.Errorf("Failed to delete users with error: %v", err)
+	}
+	fmt.Println("All users have been deleted. The next user created with 'register' becomes admin")
+	return nil
+}
+
+// resetPasswordCommand creates a one-time token with which the user chooses a
+// new password at login. The old password and every credential of the user
+// stop working.
+func resetPasswordCommand(state *State, arguments []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "skip the confirmation")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) == 0 || arguments[0] == "" {
+		return fmt.Errorf("User name input is missing")
+	}
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'reset-password' command expects a single argument")
+	}
+	userName := arguments[0]
+
+	ctx := context.Background()
+	target, err := state.db.GetUser(ctx, userName)
+	if err != nil {
+		return fmt.Errorf("User with name '%s' does not exist", userName)
+	}
+	admin, err := passwordResetAdmin(ctx, state)
+	if err != nil {
+		bootstrap, countErr := canBootstrapAdminPassword(ctx, state, target)
+		if countErr != nil {
+			return countErr
+		}
+		if !bootstrap {
+			return err
+		}
+		return bootstrapAdminPassword(ctx, state, target)
+	}
+	question := fmt.Sprintf("This logs '%s' out everywhere and revokes their password, api tokens and Fever key.", userName)
+	if err := confirmAction(*yes, question, userName); err != nil {
+		return err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, state, admin, "reset-password", userName); err != nil {
+		return err
+	}
+	if err := state.db.DeleteUserPassword(ctx, target.ID); err != nil {
+		return fmt.Errorf("Failed to delete the password of '%s': %w", userName, err)
+	}
+	if err := revokeCredentials(ctx, state, target); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := state.db.CreatePasswordReset(ctx, database.CreatePasswordResetParams{
+		UserID:    target.ID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordResetDuration),
+	}); err != nil {
+		return fmt.Errorf("Failed to create the one-time token: %w", err)
+	}
+
+	fmt.Printf("'%s' can choose a new password with 'login %s' and this one-time token, valid for %s:\n", userName, userName, passwordResetDuration)
+	fmt.Println(token)
+	return nil
+}
+
+// passwordResetAdmin returns the logged in admin that resets a password
+func passwordResetAdmin(ctx context.Context, state *State) (database.User, error) {
+	user, scope, err := currentUser(ctx, state)
+	if err != nil {
+		return database.User{}, err
+	}
+	if !scope.allows(scopeAccount) {
+		return database.User{}, permissionError(fmt.Sprintf("The command requires the '%s' scope, the credentials only have '%s'", scopeAccount, scope))
+	}
+	if !user.IsAdmin {
+		return database.User{}, permissionError(fmt.Sprintf("User '%s' is not an admin", user.Name))
+	}
+	return user, nil
+}
+
+// canBootstrapAdminPassword reports whether the target is an admin that may
+// choose the first admin password without logging in. This is only possible
+// as long as no admin has a password, e.g. right after upgrading from a
+// version without passwords, and never with credentials in the environment.
+func canBootstrapAdminPassword(ctx context.Context, state *State, target database.User) (bool, error) {
+	if !target.IsAdmin || os.Getenv(apiTokenEnv) != "" || state.config.SessionToken != "" {
+		return false, nil
+	}
+	admins, err := state.db.CountAdminsWithPassword(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Failed to count admins: %w", err)
+	}
+	return admins == 0, nil
+}
+
+// bootstrapAdminPassword lets the operator type the first admin password at
+// the local terminal. No token is handed out, so the password cannot be taken
+// over by a script or a remote caller.
+func bootstrapAdminPassword(ctx context.Context, state *State, target database.User) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("No admin has a password yet. Run 'reset-password %s' in an interactive terminal to choose the first one", target.Name)
+	}
+	question := fmt.Sprintf("No admin has a password yet. This sets the password of admin '%s'.", target.Name)
+	if err := confirmAction(false, question, target.Name); err != nil {
+		return err
+	}
+	password, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, state, target, "reset-password", "first admin password of "+target.Name); err != nil {
+		return err
+	}
+	if err := setUserPassword(ctx, state, target, password); err != nil {
+		return err
+	}
+	fmt.Printf("The password of '%s' was set, log in with 'login %s'\n", target.Name, target.Name)
+	return nil
+}
+
+// revokeCredentials ends the sessions of the user and deletes their api
+// tokens and Fever key
+func revokeCredentials(ctx context.Context, state *State, user database.User) error {
+	if err := state.db.DeleteSessionsForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("Failed to end the sessions of '%s': %w", user.Name, err)
+	}
+	if err := state.db.DeleteApiTokensForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("Failed to revoke the api tokens of '%s': %w", user.Name, err)
+	}
+	if err := state.db.DeleteFeverAccount(ctx, user.ID); err != nil {
+		return fmt.Errorf("Failed to revoke the Fever key of '%s': %w", user.Name, err)
+	}
+	return nil
+}
+
+func setAdminCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("set-admin", flag.ContinueOnError)
+	revoke := flags.Bool("revoke", false, "revoke the admin role instead of granting it")
+	yes := flags.Bool("yes", false, "skip the confirmation of --revoke")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) == 0 || arguments[0] == "" {
+		return fmt.Errorf("User name input is missing")
+	}
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'set-admin' command expects a single argument")
+	}
+	userName := arguments[0]
+
+	ctx := context.Background()
+	target, err := state.db.GetUser(ctx, userName)
+	if err != nil {
+		return fmt.Errorf("User with name '%s' does not exist", userName)
+	}
+	action := "grant-admin"
+	if *revoke {
+		action = "revoke-admin"
+		admins, err := state.db.CountAdmins(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to count admins: %w", err)
+		}
+		if target.IsAdmin && admins <= 1 {
+			return fmt.Errorf("'%s' is the last admin, grant the role to another user first", userName)
+		}
+		if err := confirmAction(*yes, fmt.Sprintf("This revokes the admin role of '%s'.", userName), userName); err != nil {
+			return err
+		}
+	}
+
+	if err := recordAudit(ctx, state, user, action, userName); err != nil {
+		return err
+	}
+	if _, err := state.db.SetUserAdmin(ctx, database.SetUserAdminParams{
+		Name:      userName,
+		IsAdmin:   !*revoke,
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("Failed to update user: %w", err)
+	}
+
+	if *revoke {
+		fmt.Printf("'%s' is no longer an admin\n", userName)
+	} else {
+		fmt.Printf("'%s' is now an admin\n", userName)
+	}
+	return nil
+}
+
+func listAuditLogCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	limit := flags.Int("limit", defaultAuditEntries, "number of entries to list")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'audit' command only accepts flags")
+	}
+	if *limit < 1 {
+		return fmt.Errorf("The limit must be a positive number")
+	}
+
+	entries, err := state.db.GetAuditEntries(context.Background(), int32(*limit))
+	if err != nil {
+		return fmt.Errorf("Failed to fetch audit log: %w", err)
+	}
+	if state.output != outputTable {
+		return writeRecords(os.Stdout, state.output, entries)
+	}
+
+	fmt.Println("Time\tUser\tAction\tDetails")
+	for _, entry := range entries {
+		fmt.Printf("%s\t%s\t%s\t%s\n", entry.CreatedAt.Format(time.DateTime), entry.UserName, entry.Action, entry.Details)
+	}
+	return nil
+}
@@ -0,0 +1,586 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/1DIce/gator/internal/metrics"
+	"github.com/1DIce/gator/internal/rss"
+	"github.com/1DIce/gator/internal/schedule"
+	"github.com/google/uuid"
+)
+
+// leaseMargin is added to the time an aggregator may need for its claimed
+// feeds, it covers storing the posts
+const leaseMargin = time.Minute
+
+type aggregateOptions struct {
+	// aggregator identifies the process in the leases of the feeds it claims
+	aggregator string
+	workers    int
+	batch      int
+	timeout    time.Duration
+	// maxFailures is the number of consecutive failures after which a feed is
+	// disabled. 0 never disables feeds.
+	maxFailures int
+	// stopping is closed by SIGINT or SIGTERM. No feeds are claimed or started
+	// afterwards, the ones being fetched may finish. Nil never stops.
+	stopping <-chan struct{}
+}
+
+type scrapeResult struct {
+	feed        database.Feed
+	notModified bool
+	newPosts    int
+	nextFetchAt time.Time
+	disabled    bool
+	interrupted bool
+	duration    time.Duration
+	err         error
+}
+
+func aggregateFeedsCommand(state *State, arguments []string) error {
+	flags := flag.NewFlagSet("agg", flag.ContinueOnError)
+	workers := flags.Int("workers", 0, "number of feeds fetched concurrently. By default a single feed is fetched per tick")
+	batchSize := flags.Int("batch", 0, "number of stale feeds claimed per tick in worker mode. Defaults to the number of workers")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of a single feed request")
+	maxFailures := flags.Int("max-failures", 10, "consecutive failures after which a feed is disabled. 0 never disables feeds")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 30*time.Second, "time the feeds being fetched get to finish after SIGINT or SIGTERM")
+	metricsAddress := flags.String("metrics-addr", "", "address of the Prometheus metrics listener, e.g. :9090. Disabled by default")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+
+	if len(arguments) == 0 || arguments[0] == "" {
+		return fmt.Errorf("Timer input is missing")
+	}
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'agg' command expects a single argument")
+	}
+	if *workers < 0 || *batchSize < 0 || *maxFailures < 0 {
+		return fmt.Errorf("The number of workers, the batch size and the failure threshold must not be negative")
+	}
+	if *batchSize == 0 {
+		*batchSize = *workers
+	}
+
+	timeBetweenRequests, err := time.ParseDuration(arguments[0])
+	if err != nil {
+		return fmt.Errorf("timer format is invalid: %w", err)
+	}
+	fmt.Printf("Collecting feeds every %s\n\n", timeBetweenRequests.String())
+
+	options := aggregateOptions{
+		aggregator:  aggregatorID(),
+		workers:     *workers,
+		batch:       *batchSize,
+		timeout:     *timeout,
+		maxFailures: *maxFailures,
+	}
+
+	// ctx is cancelled once the shutdown deadline passed after SIGINT or
+	// SIGTERM. Until then the feeds that are being fetched may finish.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	stopping := make(chan struct{})
+	options.stopping = stopping
+	// The config is reloaded between ticks, so no fetch uses a closed database
+	reload := make(chan struct{}, 1)
+	go func() {
+		for received := range signals {
+			switch {
+			case received == syscall.SIGHUP:
+				select {
+				case reload <- struct{}{}:
+				default:
+				}
+			case isClosed(stopping):
+				fmt.Printf("Received %s again, stopping immediately\n", received)
+				cancel()
+			default:
+				fmt.Printf("Received %s, finishing the current feeds within %s. Repeat to stop immediately\n", received, *shutdownTimeout)
+				close(stopping)
+				time.AfterFunc(*shutdownTimeout, cancel)
+			}
+		}
+	}()
+
+	if *metricsAddress != "" {
+		stopMetrics, err := serveMetrics(*metricsAddress)
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
+	}
+
+	ticker := time.NewTicker(timeBetweenRequests)
+	defer ticker.Stop()
+	for {
+		var err error
+		if options.workers == 0 {
+			err = scrapeFeeds(ctx, state, options)
+		} else {
+			err = scrapeFeedBatch(ctx, state, options)
+		}
+		if err != nil {
+			fmt.Println(err)
+		}
+		updateOverdueFeeds(ctx, state)
+		fmt.Println("")
+
+		for waiting := true; waiting; {
+			if !isClosed(stopping) {
+				fmt.Printf("Waiting %s to fetch the next...\n\n", timeBetweenRequests.String())
+			}
+			select {
+			case <-stopping:
+				releaseFeedLeases(state, options)
+				fmt.Println("Aggregator stopped")
+				return nil
+			case <-reload:
+				if err := reloadConfig(state); err != nil {
+					fmt.Printf("Failed to reload config, keeping the current one: %v\n", err)
+				} else {
+					fmt.Println("Reloaded config")
+				}
+			case <-ticker.C:
+				waiting = false
+			}
+		}
+	}
+}
+
+// serveMetrics starts the metrics listener and returns a function that stops
+// it. The address is bound right away, so a taken port fails the command.
+func serveMetrics(address string) (func(), error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to start metrics listener: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	server := &http.Server{Handler: mux}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Metrics listener failed: %v\n", err)
+		}
+	}()
+	fmt.Printf("Serving metrics on %s/metrics\n", listener.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
+
+// updateOverdueFeeds counts the feeds that are still due after a tick. A
+// growing number means the aggregators cannot keep up with the feeds.
+func updateOverdueFeeds(ctx context.Context, state *State) {
+	overdue, err := state.db.CountOverdueFeeds(ctx)
+	if err != nil {
+		return
+	}
+	metrics.FeedsOverdue.Set(float64(overdue))
+}
+
+func isClosed(channel <-chan struct{}) bool {
+	select {
+	case <-channel:
+		return true
+	default:
+		return false
+	}
+}
+
+func scrapeFeeds(ctx context.Context, state *State, options aggregateOptions) error {
+	if isClosed(options.stopping) {
+		return nil
+	}
+	feeds, err := claimFeeds(ctx, state, options, 1)
+	if err != nil {
+		return err
+	}
+	if len(feeds) == 0 {
+		fmt.Println("No feed is due for a refresh")
+		return nil
+	}
+
+	// The result already reports the error of the feed
+	printScrapeResult(scrapeFeed(ctx, state, feeds[0], options))
+	return nil
+}
+
+// scrapeFeedBatch claims up to options.batch feeds that are due for a refresh and
+// fetches them with the given number of concurrent workers, see claimFeeds.
+func scrapeFeedBatch(ctx context.Context, state *State, options aggregateOptions) error {
+	if isClosed(options.stopping) {
+		return nil
+	}
+	feeds, err := claimFeeds(ctx, state, options, options.batch)
+	if err != nil {
+		return err
+	}
+	if len(feeds) == 0 {
+		fmt.Println("No feed is due for a refresh")
+		return nil
+	}
+	fmt.Printf("Fetching %d feeds with %d workers\n", len(feeds), options.workers)
+
+	failed := 0
+	for result := range scrapeFeedsConcurrently(ctx, state, feeds, options) {
+		printScrapeResult(result)
+		if result.err != nil {
+			failed++
+		}
+	}
+	fmt.Printf("Fetched %d feeds, %d failed\n", len(feeds)-failed, failed)
+	return nil
+}
+
+// claimFeeds leases up to batch feeds that are due for a refresh. Claimed feeds
+// are locked with SKIP LOCKED and leased until they could have been fetched, so
+// concurrent aggregators never claim the same feed. Fetching a feed releases
+// its lease, feeds of crashed aggregators are claimed again once it expired.
+// The lease is computed by the database, so it does not depend on the clock of
+// the aggregator.
+func claimFeeds(ctx context.Context, state *State, options aggregateOptions, batch int) ([]database.Feed, error) {
+	rounds := (batch + max(options.workers, 1) - 1) / max(options.workers, 1)
+	lease := time.Duration(rounds)*options.timeout + leaseMargin
+	feeds, err := state.db.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+		ClaimedBy:    sql.NullString{String: options.aggregator, Valid: true},
+		LeaseSeconds: durationSeconds(lease),
+		BatchSize:    int32(batch),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to claim feeds to fetch: %w", err)
+	}
+	return feeds, nil
+}
+
+// releaseFeedLeases returns the feeds whose fetch was interrupted by a
+// shutdown, so other aggregators do not have to wait for the leases to expire
+func releaseFeedLeases(state *State, options aggregateOptions) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	released, err := state.db.ReleaseFeedLeases(ctx, sql.NullString{String: options.aggregator, Valid: true})
+	if err != nil {
+		fmt.Printf("Failed to release claimed feeds: %v\n", err)
+		return
+	}
+	if released > 0 {
+		fmt.Printf("Released %d unfinished feeds\n", released)
+	}
+}
+
+// releaseUnstartedFeeds releases the leases of claimed feeds whose fetch was
+// never started. Feeds leased by other aggregators are not changed.
+func releaseUnstartedFeeds(state *State, options aggregateOptions, feeds []database.Feed) {
+	if len(feeds) == 0 {
+		return
+	}
+	// ctx may already be cancelled, the leases are released regardless
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, feed := range feeds {
+		if err := state.db.ReleaseFeedLease(ctx, database.ReleaseFeedLeaseParams{
+			ID:        feed.ID,
+			ClaimedBy: sql.NullString{String: options.aggregator, Valid: true},
+		}); err != nil {
+			fmt.Printf("Failed to release claimed feed '%s': %v\n", feed.Url, err)
+			return
+		}
+	}
+	fmt.Printf("Released %d feeds that were not started\n", len(feeds))
+}
+
+// aggregatorID identifies an aggregator process across hosts
+func aggregatorID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// scrapeFeedsConcurrently fetches the feeds with options.workers workers. The
+// returned channel is closed once every feed was fetched, or once the started
+// feeds finished after options.stopping was closed or ctx was cancelled. The
+// leases of feeds that were not started are released.
+func scrapeFeedsConcurrently(ctx context.Context, state *State, feeds []database.Feed, options aggregateOptions) <-chan scrapeResult {
+	jobs := make(chan database.Feed)
+	results := make(chan scrapeResult)
+	var wg sync.WaitGroup
+	for range max(min(options.workers, len(feeds)), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				results <- scrapeFeed(ctx, state, feed, options)
+			}
+		}()
+	}
+
+	go func() {
+		started := 0
+	dispatch:
+		for _, feed := range feeds {
+			// select picks a random ready case, so a closed stopping channel
+			// would not prevent every further dispatch
+			if isClosed(options.stopping) {
+				break
+			}
+			select {
+			case jobs <- feed:
+				started++
+			case <-options.stopping:
+				break dispatch
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		close(jobs)
+		releaseUnstartedFeeds(state, options, feeds[started:])
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+func scrapeFeed(ctx context.Context, state *State, feed database.Feed, options aggregateOptions) (result scrapeResult) {
+	start := time.Now()
+	result.feed = feed
+	defer func() {
+		result.duration = time.Since(start)
+		metrics.FeedFetches.WithLabelValues(result.status()).Inc()
+	}()
+
+	fetchCtx, cancel := context.WithTimeout(ctx, options.timeout)
+	defer cancel()
+
+	fetchResult, err := rss.FetchFeed(fetchCtx, feed.Url, rss.CacheValidators{
+		ETag:         feed.Etag.String,
+		LastModified: feed.LastModified.String,
+	})
+	if err != nil {
+		// An interrupted fetch is not the fault of the feed, it is retried
+		// on the next run
+		if ctx.Err() != nil {
+			result.interrupted = true
+			result.err = fmt.Errorf("Interrupted: %w", ctx.Err())
+			return result
+		}
+		result.err = fmt.Errorf("Failed to fetch feed: %w", err)
+		result.nextFetchAt, result.disabled = recordFeedFailure(ctx, state, feed, result.err, options)
+		return result
+	}
+
+	// The update hints are only known if the server sent the feed
+	hints := rss.UpdateHints{}
+	if fetchResult.NotModified {
+		result.notModified = true
+	} else {
+		hints = fetchResult.Feed.Hints
+		result.newPosts, err = storeFeedItems(ctx, state, feed, fetchResult.Feed.Items)
+		if err != nil {
+			if ctx.Err() != nil {
+				result.interrupted = true
+				result.err = fmt.Errorf("Interrupted: %w", ctx.Err())
+				return result
+			}
+			// Posts the database rejects fail on every fetch, so the feed is
+			// backed off like a feed that cannot be fetched. This also
+			// releases its lease.
+			result.err = fmt.Errorf("Failed to store posts: %w", err)
+			result.nextFetchAt, result.disabled = recordFeedFailure(ctx, state, feed, result.err, options)
+			return result
+		}
+	}
+
+	// Only the delay is sent, the database adds it to its own clock
+	now := time.Now()
+	result.nextFetchAt = schedule.NextFetch(now, schedule.Input{
+		Override:         time.Duration(feed.RefreshIntervalMinutes.Int32) * time.Minute,
+		Hints:            hints,
+		FreshFor:         fetchResult.FreshFor,
+		PublicationDates: getRecentPublicationDates(ctx, state, feed),
+	})
+
+	fetched, err := state.db.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
+		LastFetchedAt:    sql.NullTime{Time: now, Valid: true},
+		Etag:             toNullString(fetchResult.Validators.ETag),
+		LastModified:     toNullString(fetchResult.Validators.LastModified),
+		NextFetchSeconds: durationSeconds(result.nextFetchAt.Sub(now)),
+		ID:               feed.ID,
+		ClaimedBy:        sql.NullString{String: options.aggregator, Valid: true},
+	})
+	switch {
+	case err == nil:
+		result.nextFetchAt = fetched.NextFetchAt.Time
+	case errors.Is(err, sql.ErrNoRows):
+		// The lease expired and another aggregator claimed the feed, its
+		// fetch updates the schedule
+		fmt.Printf("The lease of '%s' was taken over by another aggregator\n", feed.Url)
+	case err != nil:
+		result.err = fmt.Errorf("Failed to update last fetched timestamp: %v", err)
+	}
+
+	return result
+}
+
+// recordFeedFailure backs off the next fetch of a failing feed exponentially
+// and disables it once it reached the failure threshold
+func recordFeedFailure(ctx context.Context, state *State, feed database.Feed, fetchErr error, options aggregateOptions) (time.Time, bool) {
+	now := time.Now()
+	failures := int(feed.ConsecutiveFailures) + 1
+	backoff := schedule.Backoff(failures)
+	nextFetchAt := now.Add(backoff)
+	disabled := options.maxFailures > 0 && failures >= options.maxFailures
+
+	disabledAt := sql.NullTime{}
+	if disabled {
+		disabledAt = sql.NullTime{Time: now, Valid: true}
+	}
+
+	failed, err := state.db.MarkFeedFailed(ctx, database.MarkFeedFailedParams{
+		LastError:      sql.NullString{String: fetchErr.Error(), Valid: true},
+		LastErrorAt:    sql.NullTime{Time: now, Valid: true},
+		BackoffSeconds: durationSeconds(backoff),
+		DisabledAt:     disabledAt,
+		ID:             feed.ID,
+		ClaimedBy:      sql.NullString{String: options.aggregator, Valid: true},
+	})
+	switch {
+	case err == nil:
+		nextFetchAt = failed.NextFetchAt.Time
+	case errors.Is(err, sql.ErrNoRows):
+		fmt.Printf("The lease of '%s' was taken over by another aggregator\n", feed.Url)
+		disabled = false
+	case err != nil:
+		fmt.Printf("Failed to record the failure of '%s': %v\n", feed.Url, err)
+	}
+	return nextFetchAt, disabled
+}
+
+// durationSeconds converts a delay into the seconds that queries add to the
+// clock of the database. Delays are rounded up, so a fetch never runs early.
+func durationSeconds(delay time.Duration) int32 {
+	return int32(math.Ceil(delay.Seconds()))
+}
+
+// getRecentPublicationDates is used to estimate how often a feed publishes.
+// Errors are ignored because the schedule falls back to a default interval.
+func getRecentPublicationDates(ctx context.Context, state *State, feed database.Feed) []time.Time {
+	publishedAt, err := state.db.GetRecentPublicationDates(ctx, database.GetRecentPublicationDatesParams{
+		FeedID: feed.ID,
+		Limit:  20,
+	})
+	if err != nil {
+		return nil
+	}
+
+	dates := make([]time.Time, 0, len(publishedAt))
+	for _, date := range publishedAt {
+		if date.Valid {
+			dates = append(dates, date.Time)
+		}
+	}
+	return dates
+}
+
+// status is the label of the result in the fetch metrics
+func (result scrapeResult) status() string {
+	switch {
+	case result.interrupted:
+		return "interrupted"
+	case result.disabled:
+		return "disabled"
+	case result.err != nil:
+		return "error"
+	case result.notModified:
+		return "not_modified"
+	default:
+		return "ok"
+	}
+}
+
+func printScrapeResult(result scrapeResult) {
+	duration := result.duration.Round(time.Millisecond)
+	nextFetch := result.nextFetchAt.Format(time.DateTime)
+	switch {
+	case result.disabled:
+		fmt.Printf("[disabled] %s (%s): %v\n", result.feed.Url, duration, result.err)
+	case result.err != nil && !result.nextFetchAt.IsZero():
+		fmt.Printf("[error] %s (%s): %v, retry at %s\n", result.feed.Url, duration, result.err, nextFetch)
+	case result.err != nil:
+		fmt.Printf("[error] %s (%s): %v\n", result.feed.Url, duration, result.err)
+	case result.notModified:
+		fmt.Printf("[not modified] %s (%s), next fetch at %s\n", result.feed.Url, duration, nextFetch)
+	default:
+		fmt.Printf("[ok] %s (%s): %d new posts, next fetch at %s\n", result.feed.Url, duration, result.newPosts, nextFetch)
+	}
+}
+
+// storeFeedItems saves the items as posts of the feed and returns the number of
+// newly created posts. Posts that already exist are skipped.
+func storeFeedItems(ctx context.Context, state *State, feed database.Feed, items []rss.Item) (int, error) {
+	newPosts := 0
+	for _, feedItem := range items {
+		// Posts are unique by url across all feeds, so items without link
+		// would all collide with the first one
+		if feedItem.Link == "" {
+			fmt.Printf("Skipping '%s' of '%s', it has no link\n", feedItem.Title, feed.Url)
+			metrics.PostsStored.WithLabelValues("no_link").Inc()
+			continue
+		}
+
+		// A missing or unparsable date should not prevent storing the post
+		publishedAt, parseErr := rss.ParseDate(feedItem.PubDate)
+		if parseErr != nil {
+			fmt.Printf("Could not parse publication date of '%s': %v\n", feedItem.Title, parseErr)
+		}
+
+		_, err := state.db.CreatePost(ctx, database.CreatePostParams{
+			ID:        uuid.New(),
+			Url:       feedItem.Link,
+			Title:     feedItem.Title,
+			CreatedAt: time.Now(),
+			Description: sql.NullString{
+				String: feedItem.Description,
+				Valid:  true,
+			},
+			PublishedAt: sql.NullTime{
+				Time:  publishedAt,
+				Valid: parseErr == nil,
+			},
+			FeedID: feed.ID,
+		})
+		if err != nil {
+			// If the post already exists we are just ignoring the error
+			if !isDuplicateKeyError(err) {
+				return newPosts, fmt.Errorf("Unexpected error occurred during post creation: %w", err)
+			}
+			metrics.PostsStored.WithLabelValues("duplicate").Inc()
+			continue
+		}
+		metrics.PostsStored.WithLabelValues("inserted").Inc()
+		newPosts++
+	}
+	return newPosts, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/1DIce/gator/internal/rss"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// postsConnector is a database that only supports CreatePost. Like the posts
+// table it rejects a second post with the same url.
+type postsConnector struct {
+	urls []string
+}
+
+func (connector *postsConnector) Connect(context.Context) (driver.Conn, error) {
+	return postsConn{connector}, nil
+}
+
+func (connector *postsConnector) Driver() driver.Driver {
+	return nil
+}
+
+type postsConn struct {
+	connector *postsConnector
+}
+
+func (conn postsConn) Prepare(query string) (driver.Stmt, error) {
+	return postsStmt(conn), nil
+}
+
+func (conn postsConn) Close() error {
+	return nil
+}
+
+func (conn postsConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type postsStmt postsConn
+
+func (stmt postsStmt) Close() error {
+	return nil
+}
+
+func (stmt postsStmt) NumInput() int {
+	return -1
+}
+
+func (stmt postsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("only CreatePost is supported")
+}
+
+func (stmt postsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	url := args[1].(string)
+	for _, stored := range stmt.connector.urls {
+		if stored == url {
+			return nil, &pq.Error{Code: uniqueViolationCode}
+		}
+	}
+	stmt.connector.urls = append(stmt.connector.urls, url)
+	// id, url, title, created_at, updated_at, description, published_at,
+	// feed_id, search_vector, short_id
+	row := []driver.Value{args[0], url, args[2], args[3], args[3], args[4], args[5], args[6], nil, int64(len(stmt.connector.urls))}
+	return &postsRows{row: row}, nil
+}
+
+type postsRows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (rows *postsRows) Columns() []string {
+	return make([]string, len(rows.row))
+}
+
+func (rows *postsRows) Close() error {
+	return nil
+}
+
+func (rows *postsRows) Next(dest []driver.Value) error {
+	if rows.done {
+		return io.EOF
+	}
+	rows.done = true
+	copy(dest, rows.row)
+	return nil
+}
+
+func TestStoreFeedItemsWithoutLink(t *testing.T) {
+	connector := &postsConnector{}
+	state := &State{db: database.New(sql.OpenDB(connector))}
+	date := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC).Format(time.RFC1123Z)
+
+	feeds := []struct {
+		feed  database.Feed
+		items []rss.Item
+	}{
+		{
+			feed: database.Feed{ID: uuid.New(), Url: "https://example.com/feed.xml"},
+			items: []rss.Item{
+				{Title: "Without link", PubDate: date},
+				{Title: "With link", Link: "https://example.com/1", PubDate: date},
+			},
+		},
+		{
+			feed: database.Feed{ID: uuid.New(), Url: "https://example.org/feed.xml"},
+			items: []rss.Item{
+				{Title: "Also without link", PubDate: date},
+				{Title: "With link", Link: "https://example.org/1", PubDate: date},
+			},
+		},
+	}
+
+	for _, test := range feeds {
+		newPosts, err := storeFeedItems(context.Background(), state, test.feed, test.items)
+		if err != nil {
+			t.Fatalf("storeFeedItems(%s) returned error: %v", test.feed.Url, err)
+		}
+		if newPosts != 1 {
+			t.Errorf("storeFeedItems(%s) stored %d posts, want 1", test.feed.Url, newPosts)
+		}
+	}
+
+	// Items without link are skipped instead of being stored with an empty url
+	want := []string{"https://example.com/1", "https://example.org/1"}
+	if !reflect.DeepEqual(connector.urls, want) {
+		t.Errorf("stored urls %q, want %q", connector.urls, want)
+	}
+}
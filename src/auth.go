@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/1DIce/gator/internal/config"
+	"github.com/1DIce/gator/internal/database"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+const (
+	sessionDuration       = 30 * 24 * time.Hour
+	passwordResetDuration = 24 * time.Hour
+	minPasswordLength     = 8
+)
+
+// apiTokenEnv is the environment variable scripts pass an api token in. It
+// takes precedence over the session in the config.
+const apiTokenEnv = "GATOR_TOKEN"
+
+// accessScope limits what a user may do with a set of credentials
+type accessScope string
+
+const (
+	// scopeRead allows reading feeds, posts and their states
+	scopeRead accessScope = "read"
+	// scopeManage additionally allows following feeds and marking posts
+	scopeManage accessScope = "manage"
+	// scopeAccount additionally allows changing credentials. Only sessions
+	// have it, so a leaked token cannot create further tokens.
+	scopeAccount accessScope = "account"
+)
+
+var scopeLevels = map[accessScope]int{scopeRead: 1, scopeManage: 2, scopeAccount: 3}
+
+func (scope accessScope) allows(required accessScope) bool {
+	return scopeLevels[scope] >= scopeLevels[required]
+}
+
+// stdinReader reads passwords that are piped into gator, e.g. in scripts
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// currentUser returns the user of the api token in the environment or of the
+// session in the config
+func currentUser(ctx context.Context, state *State) (database.User, accessScope, error) {
+	if token := os.Getenv(apiTokenEnv); token != "" {
+		user, scope, err := apiTokenUser(ctx, state, token)
+		if err != nil {
+			return database.User{}, "", fmt.Errorf("Invalid %s: %w", apiTokenEnv, err)
+		}
+		return user, scope, nil
+	}
+
+	if state.config.SessionToken == "" {
+		return database.User{}, "", fmt.Errorf("You are not logged in. Log in with 'login <name>' or set %s", apiTokenEnv)
+	}
+	user, err := state.db.GetUserBySession(ctx, database.GetUserBySessionParams{
+		TokenHash: hashToken(state.config.SessionToken),
+		Now:       time.Now(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, "", fmt.Errorf("Your session has expired. Log in again with 'login <name>'")
+	}
+	if err != nil {
+		return database.User{}, "", fmt.Errorf("Failed to validate session: %w", err)
+	}
+	return user, scopeAccount, nil
+}
+
+// apiTokenUser returns the user and scope of an api token
+func apiTokenUser(ctx context.Context, state *State, token string) (database.User, accessScope, error) {
+	row, err := state.db.UseApiToken(ctx, database.UseApiTokenParams{
+		Now:       sql.NullTime{Time: time.Now(), Valid: true},
+		TokenHash: hashToken(token),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, "", fmt.Errorf("The api token does not exist or was revoked")
+	}
+	if err != nil {
+		return database.User{}, "", fmt.Errorf("Failed to validate api token: %w", err)
+	}
+	user := database.User{
+		ID:        row.ID,
+		Name:      row.Name,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+		IsAdmin:   row.IsAdmin,
+	}
+	return user, accessScope(row.Scope), nil
+}
+
+// startSession creates a session of the user and stores its token in the config
+func startSession(ctx context.Context, state *State, user database.User) error {
+	token, _, err := createSession(ctx, state, user)
+	if err != nil {
+		return err
+	}
+
+	state.config.SessionToken = token
+	if err := config.Write(*state.config); err != nil {
+		return fmt.Errorf("Failed to write config: %w", err)
+	}
+	return nil
+}
+
+// createSession creates a session of the user and returns its token and
+// expiry. Only the hash of the token is stored.
+func createSession(ctx context.Context, state *State, user database.User) (string, time.Time, error) {
+	now := time.Now()
+	if err := state.db.DeleteExpiredSessions(ctx, now); err != nil {
+		return "", time.Time{}, fmt.Errorf("Failed to delete expired sessions: %w", err)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	session, err := state.db.CreateSession(ctx, database.CreateSessionParams{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(sessionDuration),
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("Failed to create session: %w", err)
+	}
+	return token, session.ExpiresAt, nil
+}
+
+func logoutCommand(state *State, arguments []string) error {
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'logout' command expects no arguments")
+	}
+	if state.config.SessionToken == "" {
+		return fmt.Errorf("You are not logged in")
+	}
+	if err := state.db.DeleteSession(context.Background(), hashToken(state.config.SessionToken)); err != nil {
+		return fmt.Errorf("Failed to delete session: %w", err)
+	}
+
+	state.config.SessionToken = ""
+	if err := config.Write(*state.config); err != nil {
+		return fmt.Errorf("Failed to write config: %w", err)
+	}
+	fmt.Println("You have been logged out")
+	return nil
+}
+
+func changePasswordCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'passwd' command expects no arguments")
+	}
+	current, err := readPassword("Current password: ")
+	if err != nil {
+		return err
+	}
+	if err := checkPassword(context.Background(), state, user, current); err != nil {
+		return err
+	}
+	password, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := setUserPassword(context.Background(), state, user, password); err != nil {
+		return err
+	}
+	fmt.Printf("The password of '%s' has been changed\n", user.Name)
+	return nil
+}
+
+// choosePasswordWithToken lets a user without password choose one. The
+// one-time token proves that an admin allowed it, see reset-password.
+func choosePasswordWithToken(ctx context.Context, state *State, user database.User) error {
+	fmt.Printf("User '%s' has no password. Enter the one-time token an admin created with 'reset-password'\n", user.Name)
+	token, err := readPassword("One-time token: ")
+	if err != nil {
+		return err
+	}
+	password, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+	// The password is validated before the token is used up
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	used, err := state.db.UsePasswordReset(ctx, database.UsePasswordResetParams{
+		UserID:    user.ID,
+		TokenHash: hashToken(strings.TrimSpace(token)),
+		Now:       time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to check the one-time token: %w", err)
+	}
+	if used == 0 {
+		return fmt.Errorf("The one-time token is wrong or expired. An admin can create a new one with 'reset-password %s'", user.Name)
+	}
+	if err := state.db.SetUserPassword(ctx, database.SetUserPasswordParams{
+		UserID:       user.ID,
+		PasswordHash: passwordHash,
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		return fmt.Errorf("Failed to set password: %w", err)
+	}
+	return nil
+}
+
+// checkPassword compares the password with the hash of the user
+func checkPassword(ctx context.Context, state *State, user database.User, password string) error {
+	hash, err := state.db.GetUserPasswordHash(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to get password of '%s': %w", user.Name, err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return fmt.Errorf("Wrong password")
+	}
+	return nil
+}
+
+// hashPassword validates the password and returns its bcrypt hash
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", validationError(fmt.Sprintf("The password must have at least %d characters", minPasswordLength))
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		// Passwords longer than 72 bytes are rejected by bcrypt
+		return "", validationError(fmt.Sprintf("Invalid password: %v", err))
+	}
+	return string(hash), nil
+}
+
+func setUserPassword(ctx context.Context, state *State, user database.User, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := state.db.SetUserPassword(ctx, database.SetUserPasswordParams{
+		UserID:       user.ID,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		return fmt.Errorf("Failed to set password: %w", err)
+	}
+	return nil
+}
+
+// promptNewPassword asks for a password twice to catch typos
+func promptNewPassword() (string, error) {
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return "", err
+	}
+	repeated, err := readPassword("Repeat password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != repeated {
+		return "", fmt.Errorf("The passwords do not match")
+	}
+	return password, nil
+}
+
+// readPassword reads a password from the terminal without echoing it. Piped
+// input is read line by line.
+func readPassword(prompt string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := stdinReader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("Failed to read password: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read password: %w", err)
+	}
+	return string(password), nil
+}
+
+func newToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("Failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// hashToken returns the hash tokens are stored with. Tokens are random, so
+// unlike passwords they do not need a slow hash.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
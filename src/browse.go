@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// browseOptions are the filters and the page of a post listing. They are
+// shared by the 'browse' command and the posts endpoint of the api.
+type browseOptions struct {
+	feedUrl    string
+	since      string
+	until      string
+	sortOrder  string
+	unreadOnly bool
+	limit      int
+	page       int
+	// after is the id of the last post of the previous page
+	after string
+}
+
+func browsePostsCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("browse", flag.ContinueOnError)
+	unread := flags.Bool("unread", false, "only list posts that are not marked as read")
+	feedUrl := flags.String("feed", "", "only list the posts of this feed url")
+	since := flags.String("since", "", "only include posts published at or after this date")
+	until := flags.String("until", "", "only include posts published before this date")
+	sortOrder := flags.String("sort", "newest", "sort order of the posts, 'newest' or 'oldest'")
+	limit := flags.Int("limit", 2, "maximum number of posts per page")
+	page := flags.Int("page", 1, "page number, starting at 1")
+	after := flags.String("after", "", "continue after the post with this id, as printed at the end of the previous page")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+
+	// The limit used to be a positional argument
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'browse' command expects at most a limit")
+	}
+	if len(arguments) == 1 {
+		*limit, err = strconv.Atoi(arguments[0])
+		if err != nil {
+			return fmt.Errorf("The limit input is not a valid integer: %w", err)
+		}
+	}
+
+	posts, err := browsePosts(state, user, browseOptions{
+		feedUrl:    *feedUrl,
+		since:      *since,
+		until:      *until,
+		sortOrder:  *sortOrder,
+		unreadOnly: *unread,
+		limit:      *limit,
+		page:       *page,
+		after:      *after,
+	})
+	if err != nil {
+		return err
+	}
+	if state.output != outputTable {
+		return writeRecords(os.Stdout, state.output, posts)
+	}
+
+	if *unread {
+		unreadCount, err := state.db.CountUnreadPostsForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("Failed to count unread posts: %w", err)
+		}
+		fmt.Printf("%d unread posts\n", unreadCount)
+	}
+	if len(posts) == 0 {
+		fmt.Println("No posts found")
+		return nil
+	}
+
+	for _, post := range posts {
+		published := "unknown"
+		if post.PublishedAt.Valid {
+			published = post.PublishedAt.Time.Format(time.DateOnly)
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", post.Title, post.Url, post.FeedName, published)
+	}
+	if len(posts) == *limit {
+		fmt.Printf("\nNext page: --after %s\n", posts[len(posts)-1].ID)
+	}
+	return nil
+}
+
+// browsePosts returns a page of the posts of the feeds the user follows.
+// Invalid options are reported as validationError.
+func browsePosts(state *State, user database.User, options browseOptions) ([]database.BrowsePostsForUserRow, error) {
+	if options.limit < 1 || options.page < 1 {
+		return nil, validationError("The limit and the page must be positive numbers")
+	}
+	if options.after != "" && options.page > 1 {
+		return nil, validationError("The page and the post to continue after can not be combined")
+	}
+	if options.sortOrder != "newest" && options.sortOrder != "oldest" {
+		return nil, validationError(fmt.Sprintf("Unknown sort order '%s', expected 'newest' or 'oldest'", options.sortOrder))
+	}
+
+	publishedAfter, err := parseOptionalDate(options.since)
+	if err != nil {
+		return nil, validationError(fmt.Sprintf("The since date is invalid: %v", err))
+	}
+	publishedBefore, err := parseOptionalDate(options.until)
+	if err != nil {
+		return nil, validationError(fmt.Sprintf("The until date is invalid: %v", err))
+	}
+
+	afterID := uuid.NullUUID{}
+	if options.after != "" {
+		id, err := uuid.Parse(options.after)
+		if err != nil {
+			return nil, validationError(fmt.Sprintf("The post id to continue after is invalid: %v", err))
+		}
+		// An unknown cursor would silently result in an empty page
+		if _, err := state.db.GetPost(context.Background(), id); err != nil {
+			return nil, fmt.Errorf("Failed to find post '%s': %w", options.after, err)
+		}
+		afterID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	params := database.BrowsePostsForUserParams{
+		UserID:          user.ID,
+		FeedUrl:         toNullString(options.feedUrl),
+		PublishedAfter:  publishedAfter,
+		PublishedBefore: publishedBefore,
+		UnreadOnly:      options.unreadOnly,
+		AfterID:         afterID,
+		ResultLimit:     int32(options.limit),
+		ResultOffset:    int32((options.page - 1) * options.limit),
+	}
+	if options.sortOrder == "oldest" {
+		rows, err := state.db.BrowsePostsForUserOldestFirst(context.Background(), database.BrowsePostsForUserOldestFirstParams(params))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to retrieve posts: %w", err)
+		}
+		posts := make([]database.BrowsePostsForUserRow, 0, len(rows))
+		for _, row := range rows {
+			posts = append(posts, database.BrowsePostsForUserRow(row))
+		}
+		return posts, nil
+	}
+
+	posts, err := state.db.BrowsePostsForUser(context.Background(), params)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve posts: %w", err)
+	}
+	return posts, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/1DIce/gator/internal/metrics"
+	"github.com/lib/pq"
+)
+
+// 23505 is the postgres error code of a unique constraint violation
+const uniqueViolationCode = "23505"
+
+func isDuplicateKeyError(err error) bool {
+	var postgresErr *pq.Error
+	return errors.As(err, &postgresErr) && postgresErr.Code == uniqueViolationCode
+}
+
+func toNullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// metricsDB counts every failed query in the DBErrors metric, labelled with
+// the sqlc name of the query. Duplicate posts are expected and queries
+// cancelled by a shutdown are not failures, so neither is counted.
+type metricsDB struct {
+	db database.DBTX
+}
+
+func newQueries(db database.DBTX) *database.Queries {
+	return database.New(metricsDB{db})
+}
+
+func (db metricsDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := db.db.ExecContext(ctx, query, args...)
+	countDBError(ctx, query, err)
+	return result, err
+}
+
+func (db metricsDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := db.db.PrepareContext(ctx, query)
+	countDBError(ctx, query, err)
+	return stmt, err
+}
+
+func (db metricsDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	countDBError(ctx, query, err)
+	return rows, err
+}
+
+// QueryRowContext counts the error of the query itself. sql.ErrNoRows is only
+// returned by Scan and is not a failure.
+func (db metricsDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := db.db.QueryRowContext(ctx, query, args...)
+	countDBError(ctx, query, row.Err())
+	return row
+}
+
+func countDBError(ctx context.Context, query string, err error) {
+	if err == nil || ctx.Err() != nil || isDuplicateKeyError(err) {
+		return
+	}
+	metrics.DBErrors.WithLabelValues(queryName(query)).Inc()
+}
+
+// queryName returns the name of a query generated by sqlc, which starts with
+// a "-- name: CreatePost :one" comment
+func queryName(query string) string {
+	header, found := strings.CutPrefix(query, "-- name: ")
+	if !found {
+		return "unknown"
+	}
+	name, _, _ := strings.Cut(header, " ")
+	return name
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"-- name: CreatePost :one\nINSERT INTO posts", "CreatePost"},
+		{"-- name: ReleaseFeedLeases :execrows\nUPDATE feeds", "ReleaseFeedLeases"},
+		{"SELECT 1", "unknown"},
+	}
+	for _, test := range tests {
+		if got := queryName(test.query); got != test.want {
+			t.Errorf("queryName(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
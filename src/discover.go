@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/1DIce/gator/internal/rss"
+)
+
+func discoverFeeds(pageUrl string) ([]rss.DiscoveredFeed, error) {
+	fmt.Printf("'%s' is not a feed, looking for feeds on the page...\n", pageUrl)
+	feeds, err := rss.DiscoverFeeds(context.Background(), pageUrl)
+	if err != nil {
+		return nil, err
+	}
+	if len(feeds) == 0 {
+		return nil, fmt.Errorf("No feeds found on '%s'", pageUrl)
+	}
+	return feeds, nil
+}
+
+// selectFeed lets the user pick one of the discovered feeds. The prompt is
+// skipped if there is only a single feed.
+func selectFeed(feeds []rss.DiscoveredFeed) (rss.DiscoveredFeed, error) {
+	if len(feeds) == 1 {
+		fmt.Printf("Found feed '%s'\n", feeds[0].URL)
+		return feeds[0], nil
+	}
+
+	fmt.Println("Found multiple feeds:")
+	for i, feed := range feeds {
+		description := feed.Title
+		if feed.Type != "" {
+			description = strings.TrimSpace(fmt.Sprintf("%s (%s)", feed.Title, feed.Type))
+		}
+		fmt.Printf("%d) %s %s\n", i+1, feed.URL, description)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Select a feed [1-%d]: ", len(feeds))
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return rss.DiscoveredFeed{}, fmt.Errorf("No feed was selected")
+		}
+		selection, err := strconv.Atoi(strings.TrimSpace(input))
+		if err == nil && selection >= 1 && selection <= len(feeds) {
+			return feeds[selection-1], nil
+		}
+		fmt.Println("Invalid selection")
+	}
+}
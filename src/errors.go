@@ -0,0 +1,17 @@
+package main
+
+// validationError is an error in the input of a command or api request. The
+// api reports it as a bad request instead of an internal error.
+type validationError string
+
+func (err validationError) Error() string {
+	return string(err)
+}
+
+// permissionError is returned if the credentials lack the scope of a command
+// or api request. The api reports it as forbidden.
+type permissionError string
+
+func (err permissionError) Error() string {
+	return string(err)
+}
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+)
+
+// The Fever api is documented at https://feedafever.com/api. Feeds and items
+// are identified by their short ids and groups are the categories of the
+// followed feeds.
+const feverAPIVersion = 3
+
+// feverAllGroupID is the group Fever clients use for all feeds
+const feverAllGroupID = 0
+
+// feverDefaultFavicon is a transparent gif, used for sites without favicon
+const feverDefaultFavicon = "image/gif;base64,R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7"
+
+const maxFaviconSize = 64 * 1024
+
+// feverPageSize is the number of items of a response. Clients page past it
+// with since_id and max_id.
+const feverPageSize = 50
+
+// errNoFavicon is returned if a site has no usable favicon. Other errors, e.g.
+// timeouts, are transient and not cached.
+var errNoFavicon = errors.New("no favicon")
+
+type feverServer struct {
+	state    *State
+	favicons *faviconCache
+}
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64 `json:"group_id"`
+	// FeedIDs is a comma separated list
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	Url               string `json:"url"`
+	SiteUrl           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverFavicon struct {
+	ID   int64  `json:"id"`
+	Data string `json:"data"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	Html          string `json:"html"`
+	Url           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+func setFeverPasswordCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'fever-password' command expects no arguments, the password is prompted for")
+	}
+	// The password is prompted for, so it does not end up in the shell
+	// history or the process list
+	password, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		return fmt.Errorf("Password input is missing")
+	}
+
+	if err := state.db.SetFeverApiKey(context.Background(), database.SetFeverApiKeyParams{
+		UserID:    user.ID,
+		ApiKey:    feverApiKey(user.Name, password),
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("Failed to set the Fever password: %w", err)
+	}
+
+	fmt.Printf("Fever clients can now log in as '%s' with the given password\n", user.Name)
+	return nil
+}
+
+// feverApiKey is the key Fever clients send instead of the credentials. The
+// user name takes the place of the email address of the original api.
+func feverApiKey(userName string, password string) string {
+	sum := md5.Sum([]byte(userName + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+func newFeverServer(state *State, workers int) *feverServer {
+	return &feverServer{
+		state:    state,
+		favicons: &faviconCache{icons: map[string]string{}, workers: workers},
+	}
+}
+
+func (server *feverServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if err := request.ParseForm(); err != nil {
+		http.Error(writer, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	// Unauthenticated requests are answered with auth 0 instead of an error
+	response := map[string]any{"api_version": feverAPIVersion, "auth": 0}
+	user, scope, err := server.authenticate(request)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Fever authentication failed: %v", err)
+		}
+		writeJSONResponse(writer, http.StatusOK, response)
+		return
+	}
+	response["auth"] = 1
+
+	if err := server.respond(request, user, scope, response); err != nil {
+		status := apiErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("Fever request %s failed: %v", request.URL.RawQuery, err)
+		}
+		writeJSONResponse(writer, status, map[string]any{"api_version": feverAPIVersion, "auth": 1, "error": err.Error()})
+		return
+	}
+	writeJSONResponse(writer, http.StatusOK, response)
+}
+
+// authenticate accepts the api key of 'fever-password' and api tokens, which
+// scripts may send as api key directly
+func (server *feverServer) authenticate(request *http.Request) (database.User, accessScope, error) {
+	apiKey := request.PostFormValue("api_key")
+	if isApiToken(apiKey) {
+		return apiTokenUser(request.Context(), server.state, apiKey)
+	}
+	user, err := server.state.db.GetUserByFeverApiKey(request.Context(), strings.ToLower(apiKey))
+	return user, scopeManage, err
+}
+
+// respond adds the data requested by the query parameters to the response.
+// A single request may ask for several kinds of data.
+func (server *feverServer) respond(request *http.Request, user database.User, scope accessScope, response map[string]any) error {
+	ctx := request.Context()
+	query := request.URL.Query()
+
+	feeds, err := server.state.db.GetFeverFeeds(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to get feeds: %w", err)
+	}
+	lastRefreshed := int64(0)
+	for _, feed := range feeds {
+		if feed.LastFetchedAt.Valid {
+			lastRefreshed = max(lastRefreshed, feed.LastFetchedAt.Time.Unix())
+		}
+	}
+	response["last_refreshed_on_time"] = lastRefreshed
+
+	// Marking happens first, so the returned ids reflect the change
+	if request.PostFormValue("mark") != "" {
+		if !scope.allows(scopeManage) {
+			return permissionError("Marking items requires the 'manage' scope")
+		}
+		if err := server.mark(request, user, feeds, response); err != nil {
+			return err
+		}
+	}
+
+	if query.Has("groups") {
+		response["groups"], response["feeds_groups"] = feverGroups(feeds)
+	}
+	if query.Has("feeds") {
+		response["feeds"] = toFeverFeeds(feeds)
+		_, response["feeds_groups"] = feverGroups(feeds)
+	}
+	if query.Has("favicons") {
+		response["favicons"] = server.favicons.forFeeds(ctx, feeds)
+	}
+	if query.Has("items") {
+		items, total, err := server.items(request, user, request.Form)
+		if err != nil {
+			return err
+		}
+		response["items"], response["total_items"] = items, total
+	}
+	if query.Has("unread_item_ids") {
+		if err := addUnreadItemIds(ctx, server.state, user, response); err != nil {
+			return err
+		}
+	}
+	if query.Has("saved_item_ids") {
+		if err := addSavedItemIds(ctx, server.state, user, response); err != nil {
+			return err
+		}
+	}
+	if query.Has("links") {
+		// Hot links are a feature of the Fever server that is not supported
+		response["links"] = []any{}
+	}
+	return nil
+}
+
+// items returns a page of the items selected by the paging parameters. They
+// are read from the query and the form, as clients send them either way.
+func (server *feverServer) items(request *http.Request, user database.User, form url.Values) ([]feverItem, int64, error) {
+	params, err := feverItemsParams(user, form)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := server.state.db.GetFeverItems(request.Context(), params)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to get items: %w", err)
+	}
+	total, err := server.state.db.CountFeverItems(request.Context(), user.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to count items: %w", err)
+	}
+
+	items := make([]feverItem, 0, len(rows))
+	for _, row := range rows {
+		createdOn := row.CreatedAt
+		if row.PublishedAt.Valid {
+			createdOn = row.PublishedAt.Time
+		}
+		items = append(items, feverItem{
+			ID:            row.ShortID,
+			FeedID:        row.FeedShortID,
+			Title:         row.Title,
+			Html:          row.Description.String,
+			Url:           row.Url,
+			IsSaved:       feverBool(row.IsSaved),
+			IsRead:        feverBool(row.IsRead),
+			CreatedOnTime: createdOn.Unix(),
+		})
+	}
+	return items, total, nil
+}
+
+// feverItemsParams selects the items after since_id, before max_id or with
+// the ids in with_ids
+func feverItemsParams(user database.User, form url.Values) (database.GetFeverItemsParams, error) {
+	params := database.GetFeverItemsParams{UserID: user.ID, PageSize: feverPageSize}
+	for name, target := range map[string]*sql.NullInt64{"since_id": &params.SinceID, "max_id": &params.MaxID} {
+		if value := form.Get(name); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return database.GetFeverItemsParams{}, validationError(fmt.Sprintf("The %s parameter is not a valid integer", name))
+			}
+			*target = sql.NullInt64{Int64: id, Valid: true}
+		}
+	}
+	if value := form.Get("with_ids"); value != "" {
+		ids, err := parseFeverIds(value)
+		if err != nil {
+			return database.GetFeverItemsParams{}, err
+		}
+		if len(ids) > feverPageSize {
+			return database.GetFeverItemsParams{}, validationError(fmt.Sprintf("The with_ids parameter accepts at most %d ids", feverPageSize))
+		}
+		params.WithIds = ids
+	}
+	return params, nil
+}
+
+// mark handles mark=item|feed|group with as=read|unread|saved|unsaved
+func (server *feverServer) mark(request *http.Request, user database.User, feeds []database.GetFeverFeedsRow, response map[string]any) error {
+	ctx := request.Context()
+	target := request.PostFormValue("mark")
+	as := request.PostFormValue("as")
+	id, err := strconv.ParseInt(request.PostFormValue("id"), 10, 64)
+	if err != nil {
+		return validationError("The id parameter is not a valid integer")
+	}
+
+	switch target {
+	case "item":
+		post, err := server.state.db.GetPostByShortID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("Failed to find item %d: %w", id, err)
+		}
+		switch as {
+		case "read", "unread":
+			if _, err := setPostRead(server.state, request, user, post.ID, as == "read"); err != nil {
+				return err
+			}
+			return addUnreadItemIds(ctx, server.state, user, response)
+		case "saved":
+			if _, err := server.state.db.StarPost(ctx, database.StarPostParams{
+				UserID:    user.ID,
+				PostID:    post.ID,
+				CreatedAt: time.Now(),
+			}); err != nil {
+				return fmt.Errorf("Failed to star post: %w", err)
+			}
+			return addSavedItemIds(ctx, server.state, user, response)
+		case "unsaved":
+			if _, err := server.state.db.UnstarPost(ctx, database.UnstarPostParams{
+				UserID: user.ID,
+				PostID: post.ID,
+			}); err != nil {
+				return fmt.Errorf("Failed to unstar post: %w", err)
+			}
+			return addSavedItemIds(ctx, server.state, user, response)
+		}
+	case "feed", "group":
+		if as != "read" {
+			break
+		}
+		// Items that arrived after the client loaded the list stay unread
+		before := sql.NullTime{}
+		if value := request.PostFormValue("before"); value != "" {
+			timestamp, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return validationError("The before parameter is not a valid timestamp")
+			}
+			before = sql.NullTime{Time: time.Unix(timestamp, 0), Valid: true}
+		}
+
+		feedUrls := []sql.NullString{}
+		switch {
+		case target == "feed":
+			feed, err := server.state.db.GetFeedByShortID(ctx, id)
+			if err != nil {
+				return fmt.Errorf("Failed to find feed %d: %w", id, err)
+			}
+			feedUrls = append(feedUrls, toNullString(feed.Url))
+		case id == feverAllGroupID:
+			feedUrls = append(feedUrls, sql.NullString{})
+		default:
+			for _, feed := range feeds {
+				if feed.Category.Valid && feverGroupID(feed.Category.String) == id {
+					feedUrls = append(feedUrls, toNullString(feed.Url))
+				}
+			}
+		}
+
+		for _, feedUrl := range feedUrls {
+			if _, err := server.state.db.MarkAllPostsRead(ctx, database.MarkAllPostsReadParams{
+				ReadAt:          time.Now(),
+				UserID:          user.ID,
+				FeedUrl:         feedUrl,
+				PublishedBefore: before,
+			}); err != nil {
+				return fmt.Errorf("Failed to mark posts as read: %w", err)
+			}
+		}
+		return addUnreadItemIds(ctx, server.state, user, response)
+	}
+	return validationError(fmt.Sprintf("Unsupported action mark=%s as=%s", target, as))
+}
+
+func addUnreadItemIds(ctx context.Context, state *State, user database.User, response map[string]any) error {
+	ids, err := state.db.GetFeverUnreadItemIds(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to get unread items: %w", err)
+	}
+	response["unread_item_ids"] = joinFeverIds(ids)
+	return nil
+}
+
+func addSavedItemIds(ctx context.Context, state *State, user database.User, response map[string]any) error {
+	ids, err := state.db.GetFeverSavedItemIds(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to get saved items: %w", err)
+	}
+	response["saved_item_ids"] = joinFeverIds(ids)
+	return nil
+}
+
+// feverGroups returns the categories of the feeds as groups and the feeds of
+// every group
+func feverGroups(feeds []database.GetFeverFeedsRow) ([]feverGroup, []feverFeedsGroup) {
+	feedIds := map[string][]int64{}
+	for _, feed := range feeds {
+		if feed.Category.Valid && feed.Category.String != "" {
+			feedIds[feed.Category.String] = append(feedIds[feed.Category.String], feed.ShortID)
+		}
+	}
+
+	categories := make([]string, 0, len(feedIds))
+	for category := range feedIds {
+		categories = append(categories, category)
+	}
+	slices.Sort(categories)
+
+	groups := make([]feverGroup, 0, len(categories))
+	feedsGroups := make([]feverFeedsGroup, 0, len(categories))
+	for _, category := range categories {
+		id := feverGroupID(category)
+		groups = append(groups, feverGroup{ID: id, Title: category})
+		feedsGroups = append(feedsGroups, feverFeedsGroup{GroupID: id, FeedIDs: joinFeverIds(feedIds[category])})
+	}
+	return groups, feedsGroups
+}
+
+// feverGroupID derives a stable id from the category name, categories are
+// not stored in a table of their own
+func feverGroupID(category string) int64 {
+	// 0 is reserved for the group of all feeds
+	return int64(crc32.ChecksumIEEE([]byte(category))) + 1
+}
+
+func toFeverFeeds(feeds []database.GetFeverFeedsRow) []feverFeed {
+	result := make([]feverFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		lastUpdated := int64(0)
+		if feed.LastFetchedAt.Valid {
+			lastUpdated = feed.LastFetchedAt.Time.Unix()
+		}
+		result = append(result, feverFeed{
+			ID:                feed.ShortID,
+			FaviconID:         feed.ShortID,
+			Title:             feed.Name,
+			Url:               feed.Url,
+			SiteUrl:           siteUrl(feed.Url),
+			LastUpdatedOnTime: lastUpdated,
+		})
+	}
+	return result
+}
+
+// siteUrl returns the root of the website a feed belongs to
+func siteUrl(feedUrl string) string {
+	parsed, err := url.Parse(feedUrl)
+	if err != nil || parsed.Host == "" {
+		return feedUrl
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+func parseFeverIds(value string) ([]int64, error) {
+	ids := []int64{}
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, validationError(fmt.Sprintf("'%s' is not a valid item id", part))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func joinFeverIds(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+func feverBool(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// faviconCache keeps the favicons of the websites in memory. They are fetched
+// the first time a client asks for them, by at most workers requests at a
+// time.
+type faviconCache struct {
+	mu      sync.Mutex
+	icons   map[string]string
+	workers int
+}
+
+func (cache *faviconCache) forFeeds(ctx context.Context, feeds []database.GetFeverFeedsRow) []feverFavicon {
+	favicons := make([]feverFavicon, len(feeds))
+	slots := make(chan struct{}, max(cache.workers, 1))
+	var wg sync.WaitGroup
+	for i, feed := range feeds {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			favicons[i] = feverFavicon{ID: feed.ShortID, Data: cache.get(ctx, siteUrl(feed.Url))}
+		}()
+	}
+	wg.Wait()
+	return favicons
+}
+
+func (cache *faviconCache) get(ctx context.Context, site string) string {
+	cache.mu.Lock()
+	icon, ok := cache.icons[site]
+	cache.mu.Unlock()
+	if ok {
+		return icon
+	}
+
+	icon, err := fetchFavicon(ctx, site)
+	if err != nil {
+		// Transient failures are retried with the next request
+		if !errors.Is(err, errNoFavicon) {
+			return feverDefaultFavicon
+		}
+		icon = feverDefaultFavicon
+	}
+	cache.mu.Lock()
+	cache.icons[site] = icon
+	cache.mu.Unlock()
+	return icon
+}
+
+// fetchFavicon returns the favicon of a site in the "<mime type>;base64,<data>"
+// format of the Fever api
+func fetchFavicon(ctx context.Context, site string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", site+"/favicon.ico", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Add("User-Agent", "gator")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return "", fmt.Errorf("Failed to fetch favicon with status: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: '%s' answered with status %s", errNoFavicon, site, resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconSize))
+	if err != nil {
+		return "", err
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(content)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", fmt.Errorf("%w: the favicon of '%s' is not an image", errNoFavicon, site)
+	}
+	contentType, _, _ = strings.Cut(contentType, ";")
+	return contentType + ";base64," + base64.StdEncoding.EncodeToString(content), nil
+}
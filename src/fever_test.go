@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestFeverItemsParams(t *testing.T) {
+	user := database.User{ID: uuid.New()}
+	tooManyIds := make([]string, feverPageSize+1)
+	for i := range tooManyIds {
+		tooManyIds[i] = strconv.Itoa(i)
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		body    url.Values
+		want    database.GetFeverItemsParams
+		wantErr bool
+	}{
+		{
+			name:  "first page",
+			query: "items",
+			want:  database.GetFeverItemsParams{UserID: user.ID, PageSize: feverPageSize},
+		},
+		{
+			name:  "since id",
+			query: "items&since_id=50",
+			want: database.GetFeverItemsParams{
+				UserID: user.ID, PageSize: feverPageSize, SinceID: sql.NullInt64{Int64: 50, Valid: true},
+			},
+		},
+		{
+			name:  "max id",
+			query: "items&max_id=51",
+			want: database.GetFeverItemsParams{
+				UserID: user.ID, PageSize: feverPageSize, MaxID: sql.NullInt64{Int64: 51, Valid: true},
+			},
+		},
+		{
+			name:  "with ids",
+			query: "items&with_ids=3,1,2",
+			want:  database.GetFeverItemsParams{UserID: user.ID, PageSize: feverPageSize, WithIds: []int64{3, 1, 2}},
+		},
+		{
+			name:  "paging in the form",
+			query: "items",
+			body:  url.Values{"since_id": {"7"}},
+			want: database.GetFeverItemsParams{
+				UserID: user.ID, PageSize: feverPageSize, SinceID: sql.NullInt64{Int64: 7, Valid: true},
+			},
+		},
+		{name: "invalid since id", query: "items&since_id=x", wantErr: true},
+		{name: "invalid with ids", query: "items&with_ids=1,x", wantErr: true},
+		{name: "too many ids", query: "items&with_ids=" + strings.Join(tooManyIds, ","), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "/fever/?api&"+test.query, strings.NewReader(test.body.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if err := request.ParseForm(); err != nil {
+				t.Fatalf("ParseForm() returned error: %v", err)
+			}
+
+			got, err := feverItemsParams(user, request.Form)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("feverItemsParams() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("feverItemsParams() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("feverItemsParams() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
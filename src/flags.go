@@ -0,0 +1,28 @@
+package main
+
+import "flag"
+
+// parseFlags parses the flags of a command and returns the positional
+// arguments. Unlike flag.FlagSet.Parse it allows flags to appear after
+// positional arguments, e.g. "agg 1m --workers 4".
+func parseFlags(flags *flag.FlagSet, arguments []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(arguments); err != nil {
+			return nil, err
+		}
+		remaining := flags.Args()
+		if len(remaining) == 0 {
+			return positional, nil
+		}
+
+		// Everything after a "--" terminator is positional
+		consumed := len(arguments) - len(remaining)
+		if consumed > 0 && arguments[consumed-1] == "--" {
+			return append(positional, remaining...), nil
+		}
+
+		positional = append(positional, remaining[0])
+		arguments = remaining[1:]
+	}
+}
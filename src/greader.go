@@ -0,0 +1,576 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+)
+
+// The Google Reader api as implemented by FreshRSS, Miniflux and others.
+// Clients log in with the credentials set by 'fever-password' or an api token.
+const (
+	readerItemPrefix  = "tag:google.com,2005:reader/item/"
+	readerFeedPrefix  = "feed/"
+	readerLabelPrefix = "user/-/label/"
+	readerReadingList = "user/-/state/com.google/reading-list"
+	readerRead        = "user/-/state/com.google/read"
+	readerStarred     = "user/-/state/com.google/starred"
+)
+
+// readerStreamContentsPath is followed by the stream id
+const readerStreamContentsPath = "/reader/api/0/stream/contents/"
+
+const (
+	defaultReaderItems = 20
+	maxReaderItems     = 1000
+)
+
+type readerServer struct {
+	state *State
+}
+
+// readerHandler handles a request of an authenticated user and returns the
+// json response body
+type readerHandler func(request *http.Request, user database.User) (any, error)
+
+type readerCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type readerSubscription struct {
+	ID         string           `json:"id"`
+	Title      string           `json:"title"`
+	Categories []readerCategory `json:"categories"`
+	Url        string           `json:"url"`
+	HtmlUrl    string           `json:"htmlUrl"`
+	IconUrl    string           `json:"iconUrl"`
+}
+
+type readerTag struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+type readerItemRef struct {
+	ID              string   `json:"id"`
+	DirectStreamIds []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+type readerLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type readerContent struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type readerOrigin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HtmlUrl  string `json:"htmlUrl"`
+}
+
+type readerItem struct {
+	ID            string        `json:"id"`
+	CrawlTimeMsec string        `json:"crawlTimeMsec"`
+	TimestampUsec string        `json:"timestampUsec"`
+	Published     int64         `json:"published"`
+	Updated       int64         `json:"updated"`
+	Title         string        `json:"title"`
+	Canonical     []readerLink  `json:"canonical"`
+	Alternate     []readerLink  `json:"alternate"`
+	Summary       readerContent `json:"summary"`
+	Categories    []string      `json:"categories"`
+	Origin        readerOrigin  `json:"origin"`
+	Author        string        `json:"author"`
+}
+
+type readerStream struct {
+	ID           string       `json:"id"`
+	Updated      int64        `json:"updated"`
+	Items        []readerItem `json:"items"`
+	Continuation string       `json:"continuation,omitempty"`
+}
+
+func newReaderServer(state *State) *readerServer {
+	return &readerServer{state: state}
+}
+
+func (server *readerServer) register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /accounts/ClientLogin", server.clientLogin)
+	mux.HandleFunc("GET /reader/api/0/token", server.authenticated(scopeRead, server.token))
+	mux.HandleFunc("GET /reader/api/0/user-info", server.authenticated(scopeRead, server.userInfo))
+	mux.HandleFunc("GET /reader/api/0/subscription/list", server.authenticated(scopeRead, server.subscriptions))
+	mux.HandleFunc("GET /reader/api/0/tag/list", server.authenticated(scopeRead, server.tags))
+	mux.HandleFunc("GET /reader/api/0/stream/items/ids", server.authenticated(scopeRead, server.itemIds))
+	mux.HandleFunc("GET "+readerStreamContentsPath+"{stream...}", server.authenticated(scopeRead, server.streamContents))
+	mux.HandleFunc("GET /reader/api/0/stream/contents", server.authenticated(scopeRead, server.streamContents))
+	mux.HandleFunc("POST /reader/api/0/stream/items/contents", server.authenticated(scopeRead, server.itemContents))
+	mux.HandleFunc("POST /reader/api/0/edit-tag", server.authenticated(scopeManage, server.editTag))
+}
+
+// rawStreamPaths serves the stream contents before ServeMux sees the path.
+// Clients often send stream ids such as feed/https://example.com/feed.xml
+// without encoding them, and ServeMux would redirect the cleaned "//".
+func (server *readerServer) rawStreamPaths(next http.Handler) http.Handler {
+	streamContents := server.authenticated(scopeRead, server.streamContents)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		isRead := request.Method == http.MethodGet || request.Method == http.MethodHead
+		if isRead && strings.HasPrefix(request.URL.Path, readerStreamContentsPath) {
+			streamContents(writer, request)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// clientLogin exchanges the credentials for the token clients send in the
+// "Authorization: GoogleLogin auth=<token>" header. The password is either
+// the one of 'fever-password' or an api token, which is used as is.
+func (server *readerServer) clientLogin(writer http.ResponseWriter, request *http.Request) {
+	userName := request.FormValue("Email")
+	password := request.FormValue("Passwd")
+	token := password
+	var user database.User
+	var err error
+	if isApiToken(password) {
+		user, _, err = apiTokenUser(request.Context(), server.state, password)
+	} else {
+		apiKey := feverApiKey(userName, password)
+		token = userName + "/" + apiKey
+		user, err = server.state.db.GetUserByFeverApiKey(request.Context(), apiKey)
+	}
+	if err != nil || user.Name != userName {
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Google Reader login failed: %v", err)
+		}
+		http.Error(writer, "Error=BadAuthentication", http.StatusUnauthorized)
+		return
+	}
+
+	if request.FormValue("output") == "json" {
+		writeJSONResponse(writer, http.StatusOK, map[string]string{"SID": token, "LSID": token, "Auth": token})
+		return
+	}
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(writer, "SID=%s\nLSID=%s\nAuth=%s\n", token, token, token)
+}
+
+// authenticated requires credentials with at least the given scope
+func (server *readerServer) authenticated(required accessScope, handler readerHandler) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		user, scope, err := server.authenticate(request)
+		if err != nil {
+			http.Error(writer, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !scope.allows(required) {
+			http.Error(writer, fmt.Sprintf("The request requires the '%s' scope", required), http.StatusForbidden)
+			return
+		}
+
+		response, err := handler(request, user)
+		if err != nil {
+			status := apiErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Printf("%s %s failed: %v", request.Method, request.URL.Path, err)
+			}
+			http.Error(writer, err.Error(), status)
+			return
+		}
+		// Actions are confirmed with a plain text OK
+		if text, ok := response.(string); ok {
+			writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(writer, text)
+			return
+		}
+		writeJSONResponse(writer, http.StatusOK, response)
+	}
+}
+
+func (server *readerServer) authenticate(request *http.Request) (database.User, accessScope, error) {
+	token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "GoogleLogin auth=")
+	if !ok {
+		return database.User{}, "", fmt.Errorf("Authorization header is missing")
+	}
+	if isApiToken(token) {
+		return apiTokenUser(request.Context(), server.state, token)
+	}
+	userName, apiKey, ok := strings.Cut(token, "/")
+	if !ok {
+		return database.User{}, "", fmt.Errorf("Invalid token")
+	}
+	user, err := server.state.db.GetUserByFeverApiKey(request.Context(), apiKey)
+	if err != nil {
+		return database.User{}, "", err
+	}
+	if user.Name != userName {
+		return database.User{}, "", fmt.Errorf("Invalid token")
+	}
+	return user, scopeManage, nil
+}
+
+// token returns the token of edit requests. Requests are already
+// authenticated by their header, so it is not checked.
+func (server *readerServer) token(request *http.Request, user database.User) (any, error) {
+	return user.ID.String() + "\n", nil
+}
+
+func (server *readerServer) userInfo(request *http.Request, user database.User) (any, error) {
+	return map[string]string{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     "",
+	}, nil
+}
+
+func (server *readerServer) subscriptions(request *http.Request, user database.User) (any, error) {
+	feeds, err := server.state.db.GetFeverFeeds(request.Context(), user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get feeds: %w", err)
+	}
+
+	subscriptions := make([]readerSubscription, 0, len(feeds))
+	for _, feed := range feeds {
+		categories := []readerCategory{}
+		if feed.Category.Valid && feed.Category.String != "" {
+			categories = append(categories, readerCategory{
+				ID:    readerLabelPrefix + feed.Category.String,
+				Label: feed.Category.String,
+			})
+		}
+		site := siteUrl(feed.Url)
+		subscriptions = append(subscriptions, readerSubscription{
+			ID:         readerFeedPrefix + feed.Url,
+			Title:      feed.Name,
+			Categories: categories,
+			Url:        feed.Url,
+			HtmlUrl:    site,
+			IconUrl:    site + "/favicon.ico",
+		})
+	}
+	return map[string]any{"subscriptions": subscriptions}, nil
+}
+
+func (server *readerServer) tags(request *http.Request, user database.User) (any, error) {
+	feeds, err := server.state.db.GetFeverFeeds(request.Context(), user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get feeds: %w", err)
+	}
+
+	categories := []string{}
+	for _, feed := range feeds {
+		if feed.Category.Valid && feed.Category.String != "" && !slices.Contains(categories, feed.Category.String) {
+			categories = append(categories, feed.Category.String)
+		}
+	}
+	slices.Sort(categories)
+
+	tags := []readerTag{{ID: readerStarred}}
+	for _, category := range categories {
+		tags = append(tags, readerTag{ID: readerLabelPrefix + category, Type: "folder"})
+	}
+	return map[string]any{"tags": tags}, nil
+}
+
+func (server *readerServer) itemIds(request *http.Request, user database.User) (any, error) {
+	rows, continuation, err := server.queryStream(request, user, request.FormValue("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]readerItemRef, 0, len(rows))
+	for _, row := range rows {
+		refs = append(refs, readerItemRef{
+			ID:              strconv.FormatInt(row.ShortID, 10),
+			DirectStreamIds: []string{},
+			TimestampUsec:   strconv.FormatInt(readerItemTime(row).UnixMicro(), 10),
+		})
+	}
+	response := map[string]any{"itemRefs": refs}
+	if continuation != "" {
+		response["continuation"] = continuation
+	}
+	return response, nil
+}
+
+func (server *readerServer) streamContents(request *http.Request, user database.User) (any, error) {
+	streamID, err := readerPathStream(request)
+	if err != nil {
+		return nil, err
+	}
+	if streamID == "" {
+		streamID = request.FormValue("s")
+	}
+	rows, continuation, err := server.queryStream(request, user, streamID)
+	if err != nil {
+		return nil, err
+	}
+	return readerStream{
+		ID:           streamID,
+		Updated:      time.Now().Unix(),
+		Items:        toReaderItems(rows),
+		Continuation: continuation,
+	}, nil
+}
+
+// readerPathStream returns the stream id that follows the stream contents
+// path. It is read from the escaped path, which keeps "//" and encoded
+// slashes as the client sent them.
+func readerPathStream(request *http.Request) (string, error) {
+	escaped, ok := strings.CutPrefix(request.URL.EscapedPath(), readerStreamContentsPath)
+	if !ok {
+		return "", nil
+	}
+	streamID, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", validationError(fmt.Sprintf("The stream id '%s' is not encoded correctly", escaped))
+	}
+	return streamID, nil
+}
+
+// itemContents returns the items of the "i" parameters
+func (server *readerServer) itemContents(request *http.Request, user database.User) (any, error) {
+	if err := request.ParseForm(); err != nil {
+		return nil, validationError("Invalid form")
+	}
+	ids, err := parseReaderItemIds(request.PostForm["i"])
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return readerStream{ID: readerReadingList, Updated: time.Now().Unix(), Items: []readerItem{}}, nil
+	}
+
+	rows, err := server.state.db.GetReaderItems(request.Context(), database.GetReaderItemsParams{
+		UserID:      user.ID,
+		WithIds:     ids,
+		ResultLimit: int32(len(ids)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get items: %w", err)
+	}
+	return readerStream{ID: readerReadingList, Updated: time.Now().Unix(), Items: toReaderItems(rows)}, nil
+}
+
+// editTag adds (a) or removes (r) the read and starred states of the items (i)
+func (server *readerServer) editTag(request *http.Request, user database.User) (any, error) {
+	if err := request.ParseForm(); err != nil {
+		return nil, validationError("Invalid form")
+	}
+	ids, err := parseReaderItemIds(request.PostForm["i"])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		post, err := server.state.db.GetPostByShortID(request.Context(), id)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to find item %d: %w", id, err)
+		}
+		for _, tag := range request.PostForm["a"] {
+			if err := server.setTag(request, user, post, tag, true); err != nil {
+				return nil, err
+			}
+		}
+		for _, tag := range request.PostForm["r"] {
+			if err := server.setTag(request, user, post, tag, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return "OK", nil
+}
+
+func (server *readerServer) setTag(request *http.Request, user database.User, post database.Post, tag string, add bool) error {
+	switch normalizeReaderStream(tag) {
+	case readerRead:
+		_, err := setPostRead(server.state, request, user, post.ID, add)
+		return err
+	case readerStarred:
+		if add {
+			_, err := server.state.db.StarPost(request.Context(), database.StarPostParams{
+				UserID:    user.ID,
+				PostID:    post.ID,
+				CreatedAt: time.Now(),
+			})
+			return err
+		}
+		_, err := server.state.db.UnstarPost(request.Context(), database.UnstarPostParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+		return err
+	default:
+		// Labels of single items are not supported, ignoring them keeps
+		// clients in sync with the read and starred states
+		return nil
+	}
+}
+
+// queryStream returns a page of the stream and the continuation of the next page.
+// Supported parameters are n (count), c (continuation), r=o (oldest first),
+// xt and it (exclude and include a state), ot and nt (oldest and newest time).
+func (server *readerServer) queryStream(request *http.Request, user database.User, streamID string) ([]database.GetReaderItemsRow, string, error) {
+	params := database.GetReaderItemsParams{
+		UserID:      user.ID,
+		OldestFirst: request.FormValue("r") == "o",
+		ResultLimit: defaultReaderItems,
+	}
+
+	switch stream := normalizeReaderStream(streamID); {
+	case stream == "" || stream == readerReadingList:
+	case stream == readerRead:
+		params.IsRead = sql.NullBool{Bool: true, Valid: true}
+	case stream == readerStarred:
+		params.IsStarred = sql.NullBool{Bool: true, Valid: true}
+	case strings.HasPrefix(stream, readerFeedPrefix):
+		params.FeedUrl = toNullString(strings.TrimPrefix(stream, readerFeedPrefix))
+	case strings.HasPrefix(stream, readerLabelPrefix):
+		params.Category = toNullString(strings.TrimPrefix(stream, readerLabelPrefix))
+	default:
+		return nil, "", validationError(fmt.Sprintf("Unknown stream '%s'", streamID))
+	}
+
+	for _, state := range request.Form["xt"] {
+		switch normalizeReaderStream(state) {
+		case readerRead:
+			params.IsRead = sql.NullBool{Bool: false, Valid: true}
+		case readerStarred:
+			params.IsStarred = sql.NullBool{Bool: false, Valid: true}
+		}
+	}
+	for _, state := range request.Form["it"] {
+		switch normalizeReaderStream(state) {
+		case readerRead:
+			params.IsRead = sql.NullBool{Bool: true, Valid: true}
+		case readerStarred:
+			params.IsStarred = sql.NullBool{Bool: true, Valid: true}
+		}
+	}
+
+	if value := request.FormValue("n"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 1 {
+			return nil, "", validationError("The n parameter must be a positive number")
+		}
+		params.ResultLimit = int32(min(count, maxReaderItems))
+	}
+	for name, target := range map[string]*sql.NullTime{"ot": &params.PublishedAfter, "nt": &params.PublishedBefore} {
+		if value := request.FormValue(name); value != "" {
+			timestamp, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, "", validationError(fmt.Sprintf("The %s parameter is not a valid timestamp", name))
+			}
+			*target = sql.NullTime{Time: time.Unix(timestamp, 0), Valid: true}
+		}
+	}
+	if value := request.FormValue("c"); value != "" {
+		continueAfter, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, "", validationError("The continuation is invalid")
+		}
+		params.ContinueAfter = sql.NullInt64{Int64: continueAfter, Valid: true}
+	}
+
+	rows, err := server.state.db.GetReaderItems(request.Context(), params)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to get items: %w", err)
+	}
+	continuation := ""
+	if len(rows) == int(params.ResultLimit) {
+		continuation = strconv.FormatInt(rows[len(rows)-1].ShortID, 10)
+	}
+	return rows, continuation, nil
+}
+
+// normalizeReaderStream replaces the user id of a stream id with "-"
+func normalizeReaderStream(streamID string) string {
+	if !strings.HasPrefix(streamID, "user/") {
+		return streamID
+	}
+	parts := strings.SplitN(streamID, "/", 3)
+	if len(parts) < 3 {
+		return streamID
+	}
+	return "user/-/" + parts[2]
+}
+
+func toReaderItems(rows []database.GetReaderItemsRow) []readerItem {
+	items := make([]readerItem, 0, len(rows))
+	for _, row := range rows {
+		categories := []string{readerReadingList}
+		if row.Category.Valid && row.Category.String != "" {
+			categories = append(categories, readerLabelPrefix+row.Category.String)
+		}
+		if row.IsRead {
+			categories = append(categories, readerRead)
+		}
+		if row.IsStarred {
+			categories = append(categories, readerStarred)
+		}
+
+		timestamp := readerItemTime(row)
+		items = append(items, readerItem{
+			ID:            fmt.Sprintf("%s%016x", readerItemPrefix, row.ShortID),
+			CrawlTimeMsec: strconv.FormatInt(row.CreatedAt.UnixMilli(), 10),
+			TimestampUsec: strconv.FormatInt(timestamp.UnixMicro(), 10),
+			Published:     timestamp.Unix(),
+			Updated:       timestamp.Unix(),
+			Title:         row.Title,
+			Canonical:     []readerLink{{Href: row.Url}},
+			Alternate:     []readerLink{{Href: row.Url, Type: "text/html"}},
+			Summary:       readerContent{Direction: "ltr", Content: row.Description.String},
+			Categories:    categories,
+			Origin: readerOrigin{
+				StreamID: readerFeedPrefix + row.FeedUrl,
+				Title:    row.FeedName,
+				HtmlUrl:  siteUrl(row.FeedUrl),
+			},
+		})
+	}
+	return items
+}
+
+func readerItemTime(row database.GetReaderItemsRow) time.Time {
+	if row.PublishedAt.Valid {
+		return row.PublishedAt.Time
+	}
+	return row.CreatedAt
+}
+
+// parseReaderItemIds accepts the long form "tag:google.com,2005:reader/item/<hex>"
+// and the decimal short form of item ids
+func parseReaderItemIds(values []string) ([]int64, error) {
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		var id int64
+		var err error
+		if hex, ok := strings.CutPrefix(value, readerItemPrefix); ok {
+			var unsigned uint64
+			unsigned, err = strconv.ParseUint(hex, 16, 64)
+			id = int64(unsigned)
+		} else {
+			id, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return nil, validationError(fmt.Sprintf("'%s' is not a valid item id", value))
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
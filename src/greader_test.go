@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReaderPathStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"encoded feed", "/reader/api/0/stream/contents/feed%2Fhttps%3A%2F%2Fexample.com%2Ffeed.xml", "feed/https://example.com/feed.xml", false},
+		{"unencoded feed", "/reader/api/0/stream/contents/feed/https://example.com/feed.xml", "feed/https://example.com/feed.xml", false},
+		{"encoded url", "/reader/api/0/stream/contents/feed/https%3A%2F%2Fexample.com%2Ffeed.xml", "feed/https://example.com/feed.xml", false},
+		{"label", "/reader/api/0/stream/contents/user/-/label/News", "user/-/label/News", false},
+		{"no stream", "/reader/api/0/stream/contents", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", test.path, nil)
+			got, err := readerPathStream(request)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("readerPathStream() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("readerPathStream() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRawStreamPathsAreNotRedirected(t *testing.T) {
+	server := newReaderServer(&State{})
+	mux := http.NewServeMux()
+	server.register(mux)
+	handler := server.rawStreamPaths(mux)
+
+	// Without credentials the stream is not served, but it must not be
+	// redirected to the path ServeMux cleaned either
+	request := httptest.NewRequest("GET", "/reader/api/0/stream/contents/feed/https://example.com/feed.xml", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d (Location %q), want %d", recorder.Code, recorder.Header().Get("Location"), http.StatusUnauthorized)
+	}
+
+	// Other routes still go through ServeMux
+	request = httptest.NewRequest("GET", "/reader/api/0/unknown", nil)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
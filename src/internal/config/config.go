@@ -6,8 +6,9 @@ import (
 )
 
 type Config struct {
-	DbURL           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DbURL string `json:"db_url"`
+	// SessionToken identifies the session of the logged in user, see the sessions table
+	SessionToken string `json:"session_token"`
 }
 
 func Read() (Config, error) {
@@ -39,10 +40,12 @@ func Write(config Config) error {
 		return err
 	}
 
-	if err := os.WriteFile(configPath, jsonConfig, 0o666); err != nil {
+	// The config contains the session token, so only the user may read it.
+	// WriteFile keeps the permissions of existing files.
+	if err := os.WriteFile(configPath, jsonConfig, 0o600); err != nil {
 		return err
 	}
-	return nil
+	return os.Chmod(configPath, 0o600)
 }
 
 func getConfigFilePath() (string, error) {
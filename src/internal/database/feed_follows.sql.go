@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,31 +23,32 @@ WITH inserted_feed_follow AS (
         $4,
         $5
     )
-    RETURNING id, feed_id, user_id, created_at, updated_at
+    RETURNING id, feed_id, user_id, created_at, updated_at, category
 )
 
-SELECT inserted_feed_follow.id, inserted_feed_follow.feed_id, inserted_feed_follow.user_id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, feeds.name as feed_name, users.name as user_name
+SELECT inserted_feed_follow.id, inserted_feed_follow.feed_id, inserted_feed_follow.user_id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.category, feeds.name as feed_name, users.name as user_name
 FROM inserted_feed_follow
 INNER JOIN users ON inserted_feed_follow.user_id = users.id
 INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id
 `
 
 type CreateFeedFollowParams struct {
-	ID        uuid.UUID
-	FeedID    uuid.UUID
-	UserID    uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID        uuid.UUID `json:"id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	FeedID    uuid.UUID
-	UserID    uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	FeedName  string
-	UserName  string
+	ID        uuid.UUID      `json:"id"`
+	FeedID    uuid.UUID      `json:"feed_id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Category  sql.NullString `json:"category"`
+	FeedName  string         `json:"feed_name"`
+	UserName  string         `json:"user_name"`
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -64,6 +66,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Category,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -76,12 +79,12 @@ USING feeds
   WHERE feed_follows.feed_id = feeds.id AND
   feed_follows.user_id = $1 AND
   feeds.url = $2
-RETURNING feed_follows.id, feed_follows.feed_id, feed_follows.user_id, feed_follows.created_at, feed_follows.updated_at
+RETURNING feed_follows.id, feed_follows.feed_id, feed_follows.user_id, feed_follows.created_at, feed_follows.updated_at, feed_follows.category
 `
 
 type DeleteFeedFollowParams struct {
-	UserID  uuid.UUID
-	FeedUrl string
+	UserID  uuid.UUID `json:"user_id"`
+	FeedUrl string    `json:"feed_url"`
 }
 
 func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) (FeedFollow, error) {
@@ -93,12 +96,13 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Category,
 	)
 	return i, err
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.feed_id, feed_follows.user_id, feed_follows.created_at, feed_follows.updated_at, feeds.name as feed_name, users.name as user_name
+SELECT feed_follows.id, feed_follows.feed_id, feed_follows.user_id, feed_follows.created_at, feed_follows.updated_at, feed_follows.category, feeds.name as feed_name, feeds.url as feed_url, users.name as user_name
 FROM feed_follows
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
@@ -108,13 +112,15 @@ WHERE users.id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	FeedID    uuid.UUID
-	UserID    uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	FeedName  string
-	UserName  string
+	ID        uuid.UUID      `json:"id"`
+	FeedID    uuid.UUID      `json:"feed_id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Category  sql.NullString `json:"category"`
+	FeedName  string         `json:"feed_name"`
+	FeedUrl   string         `json:"feed_url"`
+	UserName  string         `json:"user_name"`
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, id uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -132,7 +138,9 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, id uuid.UUID) ([]Ge
 			&i.UserID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Category,
 			&i.FeedName,
+			&i.FeedUrl,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -147,3 +155,26 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, id uuid.UUID) ([]Ge
 	}
 	return items, nil
 }
+
+const setFeedFollowCategory = `-- name: SetFeedFollowCategory :exec
+UPDATE feed_follows
+SET category = $3, updated_at = $4
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowCategoryParams struct {
+	UserID    uuid.UUID      `json:"user_id"`
+	FeedID    uuid.UUID      `json:"feed_id"`
+	Category  sql.NullString `json:"category"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+func (q *Queries) SetFeedFollowCategory(ctx context.Context, arg SetFeedFollowCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFollowCategory,
+		arg.UserID,
+		arg.FeedID,
+		arg.Category,
+		arg.UpdatedAt,
+	)
+	return err
+}
@@ -13,6 +13,84 @@ import (
 	"github.com/google/uuid"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET claimed_by = $1, lease_expires_at = now() + $2::integer * interval '1 second'
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE disabled_at IS NULL AND (next_fetch_at IS NULL OR next_fetch_at <= now())
+      AND (lease_expires_at IS NULL OR lease_expires_at <= now())
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, url, name, created_at, updated_at, user_id, last_fetched_at, etag, last_modified, next_fetch_at, refresh_interval_minutes, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at, short_id, claimed_by, lease_expires_at
+`
+
+type ClaimFeedsToFetchParams struct {
+	ClaimedBy    sql.NullString `json:"claimed_by"`
+	LeaseSeconds int32          `json:"lease_seconds"`
+	BatchSize    int32          `json:"batch_size"`
+}
+
+// Leases are compared with the clock of the database, so aggregators with
+// skewed clocks agree on when a lease expired
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.ClaimedBy, arg.LeaseSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.RefreshIntervalMinutes,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.ShortID,
+			&i.ClaimedBy,
+			&i.LeaseExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countOverdueFeeds = `-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE disabled_at IS NULL AND (next_fetch_at IS NULL OR next_fetch_at <= now())
+  AND (lease_expires_at IS NULL OR lease_expires_at <= now())
+`
+
+func (q *Queries) CountOverdueFeeds(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOverdueFeeds)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, url,name,created_at, updated_at, user_id)
 VALUES (
@@ -23,16 +101,16 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, url, name, created_at, updated_at, user_id, last_fetched_at
+RETURNING id, url, name, created_at, updated_at, user_id, last_fetched_at, etag, last_modified, next_fetch_at, refresh_interval_minutes, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at, short_id, claimed_by, lease_expires_at
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	Url       string
-	Name      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
+	ID        uuid.UUID `json:"id"`
+	Url       string    `json:"url"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -53,12 +131,63 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.RefreshIntervalMinutes,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.ShortID,
+		&i.ClaimedBy,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const enableFeed = `-- name: EnableFeed :one
+UPDATE feeds
+SET disabled_at = NULL, consecutive_failures = 0, next_fetch_at = NULL, updated_at = $2
+WHERE url = $1
+RETURNING id, url, name, created_at, updated_at, user_id, last_fetched_at, etag, last_modified, next_fetch_at, refresh_interval_minutes, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at, short_id, claimed_by, lease_expires_at
+`
+
+type EnableFeedParams struct {
+	Url       string    `json:"url"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) EnableFeed(ctx context.Context, arg EnableFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, enableFeed, arg.Url, arg.UpdatedAt)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.RefreshIntervalMinutes,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.ShortID,
+		&i.ClaimedBy,
+		&i.LeaseExpiresAt,
 	)
 	return i, err
 }
 
 const getFeed = `-- name: GetFeed :one
-SELECT id, url, name, created_at, updated_at, user_id, last_fetched_at FROM feeds
+SELECT id, url, name, created_at, updated_at, user_id, last_fetched_at, etag, last_modified, next_fetch_at, refresh_interval_minutes, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at, short_id, claimed_by, lease_expires_at FROM feeds
 WHERE url = $1 LIMIT 1
 `
 
@@ -73,18 +202,29 @@ func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.RefreshIntervalMinutes,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.ShortID,
+		&i.ClaimedBy,
+		&i.LeaseExpiresAt,
 	)
 	return i, err
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, url, name, created_at, updated_at, user_id, last_fetched_at FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
+const getFeedByShortID = `-- name: GetFeedByShortID :one
+SELECT id, url, name, created_at, updated_at, user_id, last_fetched_at, etag, last_modified, next_fetch_at, refresh_interval_minutes, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at, short_id, claimed_by, lease_expires_at FROM feeds
+WHERE short_id = $1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+func (q *Queries) GetFeedByShortID(ctx context.Context, shortID int64) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByShortID, shortID)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -94,19 +234,79 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.RefreshIntervalMinutes,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.ShortID,
+		&i.ClaimedBy,
+		&i.LeaseExpiresAt,
 	)
 	return i, err
 }
 
+const listFeedHealth = `-- name: ListFeedHealth :many
+SELECT url, name, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at
+FROM feeds
+WHERE consecutive_failures > 0 OR disabled_at IS NOT NULL
+ORDER BY disabled_at ASC NULLS LAST, consecutive_failures DESC
+`
+
+type ListFeedHealthRow struct {
+	Url                 string         `json:"url"`
+	Name                string         `json:"name"`
+	ConsecutiveFailures int32          `json:"consecutive_failures"`
+	LastError           sql.NullString `json:"last_error"`
+	LastErrorAt         sql.NullTime   `json:"last_error_at"`
+	LastSuccessAt       sql.NullTime   `json:"last_success_at"`
+	DisabledAt          sql.NullTime   `json:"disabled_at"`
+}
+
+func (q *Queries) ListFeedHealth(ctx context.Context) ([]ListFeedHealthRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedHealth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFeedHealthRow
+	for rows.Next() {
+		var i ListFeedHealthRow
+		if err := rows.Scan(
+			&i.Url,
+			&i.Name,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listFeeds = `-- name: ListFeeds :many
 SELECT feeds.url, feeds.name, users.name as user_name from feeds
 INNER JOIN users ON feeds.user_id = users.id
 `
 
 type ListFeedsRow struct {
-	Url      string
-	Name     string
-	UserName string
+	Url      string `json:"url"`
+	Name     string `json:"name"`
+	UserName string `json:"user_name"`
 }
 
 func (q *Queries) ListFeeds(ctx context.Context) ([]ListFeedsRow, error) {
@@ -132,20 +332,167 @@ func (q *Queries) ListFeeds(ctx context.Context) ([]ListFeedsRow, error) {
 	return items, nil
 }
 
+const markFeedFailed = `-- name: MarkFeedFailed :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1, last_error = $1, last_error_at = $2,
+    next_fetch_at = now() + $3::integer * interval '1 second',
+    disabled_at = $4, updated_at = $2, claimed_by = NULL, lease_expires_at = NULL
+WHERE id = $5 AND (claimed_by IS NULL OR claimed_by = $6)
+RETURNING id, url, name, created_at, updated_at, user_id, last_fetched_at, etag, last_modified, next_fetch_at, refresh_interval_minutes, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at, short_id, claimed_by, lease_expires_at
+`
+
+type MarkFeedFailedParams struct {
+	LastError      sql.NullString `json:"last_error"`
+	LastErrorAt    sql.NullTime   `json:"last_error_at"`
+	BackoffSeconds int32          `json:"backoff_seconds"`
+	DisabledAt     sql.NullTime   `json:"disabled_at"`
+	ID             uuid.UUID      `json:"id"`
+	ClaimedBy      sql.NullString `json:"claimed_by"`
+}
+
+func (q *Queries) MarkFeedFailed(ctx context.Context, arg MarkFeedFailedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, markFeedFailed,
+		arg.LastError,
+		arg.LastErrorAt,
+		arg.BackoffSeconds,
+		arg.DisabledAt,
+		arg.ID,
+		arg.ClaimedBy,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.RefreshIntervalMinutes,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.ShortID,
+		&i.ClaimedBy,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
 const markFeedFetched = `-- name: MarkFeedFetched :one
 UPDATE feeds
-SET last_fetched_at = $2, updated_at = $2
-WHERE id = $1
-RETURNING id, url, name, created_at, updated_at, user_id, last_fetched_at
+SET last_fetched_at = $1, updated_at = $1,
+    etag = $2, last_modified = $3,
+    next_fetch_at = now() + $4::integer * interval '1 second',
+    consecutive_failures = 0, last_success_at = $1, claimed_by = NULL, lease_expires_at = NULL
+WHERE id = $5 AND (claimed_by IS NULL OR claimed_by = $6)
+RETURNING id, url, name, created_at, updated_at, user_id, last_fetched_at, etag, last_modified, next_fetch_at, refresh_interval_minutes, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at, short_id, claimed_by, lease_expires_at
 `
 
 type MarkFeedFetchedParams struct {
-	ID            uuid.UUID
-	LastFetchedAt sql.NullTime
+	LastFetchedAt    sql.NullTime   `json:"last_fetched_at"`
+	Etag             sql.NullString `json:"etag"`
+	LastModified     sql.NullString `json:"last_modified"`
+	NextFetchSeconds int32          `json:"next_fetch_seconds"`
+	ID               uuid.UUID      `json:"id"`
+	ClaimedBy        sql.NullString `json:"claimed_by"`
 }
 
+// The next fetch is scheduled with the clock of the database, like leases
 func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, markFeedFetched, arg.ID, arg.LastFetchedAt)
+	row := q.db.QueryRowContext(ctx, markFeedFetched,
+		arg.LastFetchedAt,
+		arg.Etag,
+		arg.LastModified,
+		arg.NextFetchSeconds,
+		arg.ID,
+		arg.ClaimedBy,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.RefreshIntervalMinutes,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.ShortID,
+		&i.ClaimedBy,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const releaseFeedLease = `-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET claimed_by = NULL, lease_expires_at = NULL
+WHERE id = $1 AND claimed_by = $2
+`
+
+type ReleaseFeedLeaseParams struct {
+	ID        uuid.UUID      `json:"id"`
+	ClaimedBy sql.NullString `json:"claimed_by"`
+}
+
+func (q *Queries) ReleaseFeedLease(ctx context.Context, arg ReleaseFeedLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedLease, arg.ID, arg.ClaimedBy)
+	return err
+}
+
+const releaseFeedLeases = `-- name: ReleaseFeedLeases :execrows
+UPDATE feeds
+SET claimed_by = NULL, lease_expires_at = NULL
+WHERE claimed_by = $1
+`
+
+func (q *Queries) ReleaseFeedLeases(ctx context.Context, claimedBy sql.NullString) (int64, error) {
+	result, err := q.db.ExecContext(ctx, releaseFeedLeases, claimedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedRefreshInterval = `-- name: SetFeedRefreshInterval :one
+UPDATE feeds
+SET refresh_interval_minutes = $3, next_fetch_at = $4, updated_at = $4
+WHERE url = $1 AND (user_id = $2 OR EXISTS (
+    SELECT 1 FROM users WHERE users.id = $2 AND users.is_admin
+))
+RETURNING id, url, name, created_at, updated_at, user_id, last_fetched_at, etag, last_modified, next_fetch_at, refresh_interval_minutes, consecutive_failures, last_error, last_error_at, last_success_at, disabled_at, short_id, claimed_by, lease_expires_at
+`
+
+type SetFeedRefreshIntervalParams struct {
+	Url                    string        `json:"url"`
+	UserID                 uuid.UUID     `json:"user_id"`
+	RefreshIntervalMinutes sql.NullInt32 `json:"refresh_interval_minutes"`
+	NextFetchAt            sql.NullTime  `json:"next_fetch_at"`
+}
+
+// The interval applies to every follower, so only the user who added the
+// feed and admins may change it
+func (q *Queries) SetFeedRefreshInterval(ctx context.Context, arg SetFeedRefreshIntervalParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedRefreshInterval,
+		arg.Url,
+		arg.UserID,
+		arg.RefreshIntervalMinutes,
+		arg.NextFetchAt,
+	)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -155,6 +502,18 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.RefreshIntervalMinutes,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.ShortID,
+		&i.ClaimedBy,
+		&i.LeaseExpiresAt,
 	)
 	return i, err
 }
@@ -11,38 +11,118 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID    `json:"id"`
+	UserID     uuid.UUID    `json:"user_id"`
+	Name       string       `json:"name"`
+	TokenHash  string       `json:"token_hash"`
+	Scope      string       `json:"scope"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+}
+
+type AuditLog struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Action    string    `json:"action"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	Url           string
-	Name          string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                     uuid.UUID      `json:"id"`
+	Url                    string         `json:"url"`
+	Name                   string         `json:"name"`
+	CreatedAt              time.Time      `json:"created_at"`
+	UpdatedAt              time.Time      `json:"updated_at"`
+	UserID                 uuid.UUID      `json:"user_id"`
+	LastFetchedAt          sql.NullTime   `json:"last_fetched_at"`
+	Etag                   sql.NullString `json:"etag"`
+	LastModified           sql.NullString `json:"last_modified"`
+	NextFetchAt            sql.NullTime   `json:"next_fetch_at"`
+	RefreshIntervalMinutes sql.NullInt32  `json:"refresh_interval_minutes"`
+	ConsecutiveFailures    int32          `json:"consecutive_failures"`
+	LastError              sql.NullString `json:"last_error"`
+	LastErrorAt            sql.NullTime   `json:"last_error_at"`
+	LastSuccessAt          sql.NullTime   `json:"last_success_at"`
+	DisabledAt             sql.NullTime   `json:"disabled_at"`
+	ShortID                int64          `json:"short_id"`
+	ClaimedBy              sql.NullString `json:"claimed_by"`
+	LeaseExpiresAt         sql.NullTime   `json:"lease_expires_at"`
 }
 
 type FeedFollow struct {
-	ID        uuid.UUID
-	FeedID    uuid.UUID
-	UserID    uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID        uuid.UUID      `json:"id"`
+	FeedID    uuid.UUID      `json:"feed_id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Category  sql.NullString `json:"category"`
+}
+
+type FeverAccount struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ApiKey    string    `json:"api_key"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PasswordReset struct {
+	UserID    uuid.UUID `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 type Post struct {
-	ID          uuid.UUID
-	Url         string
-	Title       string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID           uuid.UUID      `json:"id"`
+	Url          string         `json:"url"`
+	Title        string         `json:"title"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Description  sql.NullString `json:"description"`
+	PublishedAt  sql.NullTime   `json:"published_at"`
+	FeedID       uuid.UUID      `json:"feed_id"`
+	SearchVector interface{}    `json:"-"`
+	ShortID      int64          `json:"short_id"`
+}
+
+type PostState struct {
+	UserID    uuid.UUID    `json:"user_id"`
+	PostID    uuid.UUID    `json:"post_id"`
+	Read      bool         `json:"read"`
+	ReadAt    sql.NullTime `json:"read_at"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type Session struct {
+	TokenHash string    `json:"token_hash"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type StarredPost struct {
+	UserID    uuid.UUID      `json:"user_id"`
+	PostID    uuid.UUID      `json:"post_id"`
+	Note      sql.NullString `json:"note"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
 }
 
 type User struct {
-	ID        uuid.UUID
-	Name      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	IsAdmin   bool      `json:"is_admin"`
+}
+
+type UserPassword struct {
+	UserID       uuid.UUID `json:"user_id"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
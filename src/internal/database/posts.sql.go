@@ -13,6 +13,188 @@ import (
 	"github.com/google/uuid"
 )
 
+const browsePostsForUser = `-- name: BrowsePostsForUser :many
+SELECT posts.id, posts.url, posts.title, posts.created_at, posts.updated_at, posts.description, posts.published_at, posts.feed_id, feeds.name AS feed_name, feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+  AND ($2::text IS NULL OR feeds.url = $2::text)
+  AND ($3::timestamptz IS NULL OR posts.published_at >= $3::timestamptz)
+  AND ($4::timestamptz IS NULL OR posts.published_at < $4::timestamptz)
+  AND (NOT $5::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id
+    AND post_states.user_id = feed_follows.user_id
+    AND post_states.read
+  ))
+  AND ($6::uuid IS NULL OR (COALESCE(posts.published_at, '-infinity'::timestamptz), posts.id) < (
+    SELECT COALESCE(cursor_post.published_at, '-infinity'::timestamptz), cursor_post.id
+    FROM posts AS cursor_post
+    WHERE cursor_post.id = $6::uuid
+  ))
+ORDER BY COALESCE(posts.published_at, '-infinity'::timestamptz) DESC, posts.id DESC
+LIMIT $7 OFFSET $8
+`
+
+type BrowsePostsForUserParams struct {
+	UserID          uuid.UUID      `json:"user_id"`
+	FeedUrl         sql.NullString `json:"feed_url"`
+	PublishedAfter  sql.NullTime   `json:"published_after"`
+	PublishedBefore sql.NullTime   `json:"published_before"`
+	UnreadOnly      bool           `json:"unread_only"`
+	AfterID         uuid.NullUUID  `json:"after_id"`
+	ResultLimit     int32          `json:"result_limit"`
+	ResultOffset    int32          `json:"result_offset"`
+}
+
+type BrowsePostsForUserRow struct {
+	ID          uuid.UUID      `json:"id"`
+	Url         string         `json:"url"`
+	Title       string         `json:"title"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Description sql.NullString `json:"description"`
+	PublishedAt sql.NullTime   `json:"published_at"`
+	FeedID      uuid.UUID      `json:"feed_id"`
+	FeedName    string         `json:"feed_name"`
+	FeedUrl     string         `json:"feed_url"`
+}
+
+func (q *Queries) BrowsePostsForUser(ctx context.Context, arg BrowsePostsForUserParams) ([]BrowsePostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsForUser,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.PublishedAfter,
+		arg.PublishedBefore,
+		arg.UnreadOnly,
+		arg.AfterID,
+		arg.ResultLimit,
+		arg.ResultOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsForUserRow
+	for rows.Next() {
+		var i BrowsePostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Title,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const browsePostsForUserOldestFirst = `-- name: BrowsePostsForUserOldestFirst :many
+SELECT posts.id, posts.url, posts.title, posts.created_at, posts.updated_at, posts.description, posts.published_at, posts.feed_id, feeds.name AS feed_name, feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+  AND ($2::text IS NULL OR feeds.url = $2::text)
+  AND ($3::timestamptz IS NULL OR posts.published_at >= $3::timestamptz)
+  AND ($4::timestamptz IS NULL OR posts.published_at < $4::timestamptz)
+  AND (NOT $5::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id
+    AND post_states.user_id = feed_follows.user_id
+    AND post_states.read
+  ))
+  AND ($6::uuid IS NULL OR (COALESCE(posts.published_at, '-infinity'::timestamptz), posts.id) > (
+    SELECT COALESCE(cursor_post.published_at, '-infinity'::timestamptz), cursor_post.id
+    FROM posts AS cursor_post
+    WHERE cursor_post.id = $6::uuid
+  ))
+ORDER BY COALESCE(posts.published_at, '-infinity'::timestamptz) ASC, posts.id ASC
+LIMIT $7 OFFSET $8
+`
+
+type BrowsePostsForUserOldestFirstParams struct {
+	UserID          uuid.UUID      `json:"user_id"`
+	FeedUrl         sql.NullString `json:"feed_url"`
+	PublishedAfter  sql.NullTime   `json:"published_after"`
+	PublishedBefore sql.NullTime   `json:"published_before"`
+	UnreadOnly      bool           `json:"unread_only"`
+	AfterID         uuid.NullUUID  `json:"after_id"`
+	ResultLimit     int32          `json:"result_limit"`
+	ResultOffset    int32          `json:"result_offset"`
+}
+
+type BrowsePostsForUserOldestFirstRow struct {
+	ID          uuid.UUID      `json:"id"`
+	Url         string         `json:"url"`
+	Title       string         `json:"title"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Description sql.NullString `json:"description"`
+	PublishedAt sql.NullTime   `json:"published_at"`
+	FeedID      uuid.UUID      `json:"feed_id"`
+	FeedName    string         `json:"feed_name"`
+	FeedUrl     string         `json:"feed_url"`
+}
+
+func (q *Queries) BrowsePostsForUserOldestFirst(ctx context.Context, arg BrowsePostsForUserOldestFirstParams) ([]BrowsePostsForUserOldestFirstRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsForUserOldestFirst,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.PublishedAfter,
+		arg.PublishedBefore,
+		arg.UnreadOnly,
+		arg.AfterID,
+		arg.ResultLimit,
+		arg.ResultOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsForUserOldestFirstRow
+	for rows.Next() {
+		var i BrowsePostsForUserOldestFirstRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Title,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts (id, url, title, created_at, updated_at, description, published_at, feed_id)
 VALUES(
@@ -25,17 +207,17 @@ VALUES(
   $6,
   $7
 )
-RETURNING id, url, title, created_at, updated_at, description, published_at, feed_id
+RETURNING id, url, title, created_at, updated_at, description, published_at, feed_id, search_vector, short_id
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	Url         string
-	Title       string
-	CreatedAt   time.Time
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID          uuid.UUID      `json:"id"`
+	Url         string         `json:"url"`
+	Title       string         `json:"title"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Description sql.NullString `json:"description"`
+	PublishedAt sql.NullTime   `json:"published_at"`
+	FeedID      uuid.UUID      `json:"feed_id"`
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -58,12 +240,113 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.SearchVector,
+		&i.ShortID,
+	)
+	return i, err
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, url, title, created_at, updated_at, description, published_at, feed_id, search_vector, short_id FROM posts
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Title,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.SearchVector,
+		&i.ShortID,
+	)
+	return i, err
+}
+
+const getPostByShortID = `-- name: GetPostByShortID :one
+SELECT id, url, title, created_at, updated_at, description, published_at, feed_id, search_vector, short_id FROM posts
+WHERE short_id = $1
+`
+
+func (q *Queries) GetPostByShortID(ctx context.Context, shortID int64) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByShortID, shortID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Title,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.SearchVector,
+		&i.ShortID,
+	)
+	return i, err
+}
+
+const getPostByUrl = `-- name: GetPostByUrl :one
+SELECT id, url, title, created_at, updated_at, description, published_at, feed_id, search_vector, short_id FROM posts
+WHERE url = $1 LIMIT 1
+`
+
+func (q *Queries) GetPostByUrl(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByUrl, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Title,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.SearchVector,
+		&i.ShortID,
+	)
+	return i, err
+}
+
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT posts.id, posts.url, posts.title, posts.created_at, posts.updated_at, posts.description, posts.published_at, posts.feed_id, posts.search_vector, posts.short_id FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE posts.id = $1 AND feed_follows.user_id = $2
+`
+
+type GetPostForUserParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// Only posts of followed feeds are visible
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.ID, arg.UserID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Title,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.SearchVector,
+		&i.ShortID,
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.url, posts.title, posts.created_at, posts.updated_at, posts.description, posts.published_at, posts.feed_id FROM posts
+SELECT posts.id, posts.url, posts.title, posts.created_at, posts.updated_at, posts.description, posts.published_at, posts.feed_id, posts.search_vector, posts.short_id FROM posts
 INNER JOIN feed_follows
 ON posts.feed_id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1
@@ -72,8 +355,8 @@ LIMIT $2
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID uuid.UUID `json:"user_id"`
+	Limit  int32     `json:"limit"`
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
@@ -94,6 +377,113 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.SearchVector,
+			&i.ShortID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPublicationDates = `-- name: GetRecentPublicationDates :many
+SELECT published_at FROM posts
+WHERE feed_id = $1 AND published_at IS NOT NULL
+ORDER BY published_at DESC
+LIMIT $2
+`
+
+type GetRecentPublicationDatesParams struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	Limit  int32     `json:"limit"`
+}
+
+func (q *Queries) GetRecentPublicationDates(ctx context.Context, arg GetRecentPublicationDatesParams) ([]sql.NullTime, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPublicationDates, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullTime
+	for rows.Next() {
+		var published_at sql.NullTime
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPostsForUser = `-- name: SearchPostsForUser :many
+SELECT posts.id, posts.url, posts.title, posts.published_at, feeds.name AS feed_name,
+  ts_rank(posts.search_vector, websearch_to_tsquery('english', $1::text)) AS rank
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = $2
+  AND posts.search_vector @@ websearch_to_tsquery('english', $1::text)
+  AND ($3::text IS NULL OR feeds.url = $3::text)
+  AND ($4::timestamptz IS NULL OR posts.published_at >= $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR posts.published_at < $5::timestamptz)
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST
+LIMIT $6
+`
+
+type SearchPostsForUserParams struct {
+	Query           string         `json:"query"`
+	UserID          uuid.UUID      `json:"user_id"`
+	FeedUrl         sql.NullString `json:"feed_url"`
+	PublishedAfter  sql.NullTime   `json:"published_after"`
+	PublishedBefore sql.NullTime   `json:"published_before"`
+	ResultLimit     int32          `json:"result_limit"`
+}
+
+type SearchPostsForUserRow struct {
+	ID          uuid.UUID    `json:"id"`
+	Url         string       `json:"url"`
+	Title       string       `json:"title"`
+	PublishedAt sql.NullTime `json:"published_at"`
+	FeedName    string       `json:"feed_name"`
+	Rank        float32      `json:"rank"`
+}
+
+func (q *Queries) SearchPostsForUser(ctx context.Context, arg SearchPostsForUserParams) ([]SearchPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsForUser,
+		arg.Query,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.PublishedAfter,
+		arg.PublishedBefore,
+		arg.ResultLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsForUserRow
+	for rows.Next() {
+		var i SearchPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Title,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
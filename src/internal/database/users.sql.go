@@ -12,30 +12,60 @@ import (
 	"github.com/google/uuid"
 )
 
+const countAdmins = `-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE is_admin
+`
+
+func (q *Queries) CountAdmins(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAdmins)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countAdminsWithPassword = `-- name: CountAdminsWithPassword :one
+SELECT COUNT(*) FROM users
+INNER JOIN user_passwords ON users.id = user_passwords.user_id
+WHERE users.is_admin
+`
+
+func (q *Queries) CountAdminsWithPassword(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAdminsWithPassword)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5::boolean AND NOT EXISTS (SELECT 1 FROM users)
 )
-RETURNING id, name, created_at, updated_at
+RETURNING id, name, created_at, updated_at, is_admin
 `
 
 type CreateUserParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Name         string    `json:"name"`
+	AdminIfFirst bool      `json:"admin_if_first"`
 }
 
+// The first user registered at the CLI administers the instance. Users
+// registered over the api are never admins.
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
 	row := q.db.QueryRowContext(ctx, createUser,
 		arg.ID,
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.Name,
+		arg.AdminIfFirst,
 	)
 	var i User
 	err := row.Scan(
@@ -43,6 +73,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
 	)
 	return i, err
 }
@@ -57,7 +88,7 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, name, created_at, updated_at FROM users 
+SELECT id, name, created_at, updated_at, is_admin FROM users 
 WHERE name = $1 LIMIT 1
 `
 
@@ -69,12 +100,13 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, name, created_at, updated_at FROM users
+SELECT id, name, created_at, updated_at, is_admin FROM users
 `
 
 func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
@@ -91,6 +123,7 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.Name,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsAdmin,
 		); err != nil {
 			return nil, err
 		}
@@ -104,3 +137,22 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	}
 	return items, nil
 }
+
+const setUserAdmin = `-- name: SetUserAdmin :execrows
+UPDATE users SET is_admin = $2, updated_at = $3
+WHERE name = $1
+`
+
+type SetUserAdminParams struct {
+	Name      string    `json:"name"`
+	IsAdmin   bool      `json:"is_admin"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserAdmin, arg.Name, arg.IsAdmin, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
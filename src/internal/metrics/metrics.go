@@ -0,0 +1,71 @@
+// Package metrics defines the Prometheus metrics of the aggregator. They are
+// registered in their own registry, which is only served if a metrics address
+// is configured.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// FeedFetches counts scraped feeds by their result, e.g. "ok" or "error"
+	FeedFetches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_feed_fetches_total",
+		Help: "Number of feed fetches by status.",
+	}, []string{"status"})
+
+	// FetchDuration is the latency of the feed requests, including reading
+	// the response
+	FetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gator_feed_fetch_duration_seconds",
+		Help:    "Duration of feed requests by response status code.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"code"})
+
+	BytesDownloaded = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gator_feed_downloaded_bytes_total",
+		Help: "Number of bytes downloaded from feeds.",
+	})
+
+	// PostsStored counts feed items by whether they were "inserted", skipped
+	// as "duplicate" or skipped because they have "no_link"
+	PostsStored = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_posts_stored_total",
+		Help: "Number of feed items stored as posts by result.",
+	}, []string{"result"})
+
+	FeedsOverdue = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gator_feeds_overdue",
+		Help: "Number of enabled feeds that are due for a refresh and not claimed by an aggregator.",
+	})
+
+	// DBErrors counts failed database queries by query name
+	DBErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_db_errors_total",
+		Help: "Number of failed database queries by query.",
+	}, []string{"query"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		FeedFetches,
+		FetchDuration,
+		BytesDownloaded,
+		PostsStored,
+		FeedsOverdue,
+		DBErrors,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,158 @@
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CategorySeparator joins the names of nested folders into a single category.
+// Categories are exported as a single folder, so names that contain the
+// separator keep their name.
+const CategorySeparator = "/"
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+	OwnerName   string `xml:"ownerName,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Subscription is a feed outline together with the folders it is nested in
+type Subscription struct {
+	Title    string
+	XMLURL   string
+	HTMLURL  string
+	Category string
+}
+
+// Parse reads an OPML document and returns all feed subscriptions in document
+// order. Outlines without xmlUrl are treated as folders.
+func Parse(reader io.Reader) ([]Subscription, error) {
+	var document OPML
+	decoder := xml.NewDecoder(reader)
+	// OPML files exported by other readers are frequently not in UTF-8
+	decoder.CharsetReader = readLatin1
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("Failed to parse OPML document: %w", err)
+	}
+
+	subscriptions := []Subscription{}
+	collectSubscriptions(document.Body.Outlines, nil, &subscriptions)
+	return subscriptions, nil
+}
+
+// readLatin1 converts ISO-8859-1 input to UTF-8. Every latin1 byte maps to the
+// unicode code point of the same value.
+func readLatin1(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "us-ascii":
+	default:
+		return nil, fmt.Errorf("unsupported charset '%s'", charset)
+	}
+
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	runes := make([]rune, len(content))
+	for i, b := range content {
+		runes[i] = rune(b)
+	}
+	return strings.NewReader(string(runes)), nil
+}
+
+func collectSubscriptions(outlines []Outline, folders []string, subscriptions *[]Subscription) {
+	for _, outline := range outlines {
+		title := strings.TrimSpace(outline.Title)
+		if title == "" {
+			title = strings.TrimSpace(outline.Text)
+		}
+
+		if outline.XMLURL == "" {
+			nested := folders
+			if title != "" {
+				nested = append(append([]string{}, folders...), title)
+			}
+			collectSubscriptions(outline.Outlines, nested, subscriptions)
+			continue
+		}
+
+		*subscriptions = append(*subscriptions, Subscription{
+			Title:    title,
+			XMLURL:   strings.TrimSpace(outline.XMLURL),
+			HTMLURL:  strings.TrimSpace(outline.HTMLURL),
+			Category: strings.Join(folders, CategorySeparator),
+		})
+	}
+}
+
+// Write serializes the subscriptions as OPML 2.0 document. Subscriptions with
+// a category are grouped into a folder outline named like the category.
+func Write(writer io.Writer, title string, subscriptions []Subscription) error {
+	document := OPML{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, subscription := range subscriptions {
+		outline := Outline{
+			Text:    subscription.Title,
+			Title:   subscription.Title,
+			Type:    "rss",
+			XMLURL:  subscription.XMLURL,
+			HTMLURL: subscription.HTMLURL,
+		}
+
+		outlines := &document.Body.Outlines
+		if subscription.Category != "" {
+			outlines = findOrAddFolder(outlines, subscription.Category)
+		}
+		*outlines = append(*outlines, outline)
+	}
+
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
+
+func findOrAddFolder(outlines *[]Outline, name string) *[]Outline {
+	for i := range *outlines {
+		if (*outlines)[i].XMLURL == "" && (*outlines)[i].Text == name {
+			return &(*outlines)[i].Outlines
+		}
+	}
+	*outlines = append(*outlines, Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1].Outlines
+}
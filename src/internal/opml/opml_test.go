@@ -0,0 +1,91 @@
+package opml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteParseRoundTrip(t *testing.T) {
+	subscriptions := []Subscription{
+		{Title: "Uncategorized", XMLURL: "https://example.com/feed.xml"},
+		{Title: "Go blog", XMLURL: "https://go.dev/blog/feed.atom", Category: "Programming"},
+		{Title: "Rust blog", XMLURL: "https://blog.rust-lang.org/feed.xml", Category: "Programming"},
+		{Title: "News & more", XMLURL: "https://example.com/news?format=rss&lang=en", Category: "News/World"},
+		{Title: "Local news", XMLURL: "https://example.com/local.xml", Category: "News"},
+	}
+
+	var buffer bytes.Buffer
+	if err := Write(&buffer, "Feeds", subscriptions); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+
+	// "News/World" is a single folder instead of "World" nested in "News"
+	var document OPML
+	if err := xml.Unmarshal(buffer.Bytes(), &document); err != nil {
+		t.Fatalf("Failed to decode the written document: %v", err)
+	}
+	folders := []string{}
+	for _, outline := range document.Body.Outlines {
+		if outline.XMLURL == "" {
+			folders = append(folders, outline.Text)
+		}
+	}
+	if want := []string{"Programming", "News/World", "News"}; !reflect.DeepEqual(folders, want) {
+		t.Errorf("Write() created the folders %q, want %q", folders, want)
+	}
+
+	parsed, err := Parse(&buffer)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, subscriptions) {
+		t.Errorf("Parse(Write()) = %+v, want %+v", parsed, subscriptions)
+	}
+
+	// A second round trip does not change the categories
+	buffer.Reset()
+	if err := Write(&buffer, "Feeds", parsed); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	again, err := Parse(&buffer)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(again, parsed) {
+		t.Errorf("second round trip = %+v, want %+v", again, parsed)
+	}
+}
+
+func TestParseNestedFolders(t *testing.T) {
+	document := `<?xml version="1.0" encoding="ISO-8859-1"?>
+<opml version="1.0">
+  <head><title>Export</title></head>
+  <body>
+    <outline text="Tech">
+      <outline text="Go">
+        <outline text="Go blog" xmlUrl=" https://go.dev/blog/feed.atom " htmlUrl="https://go.dev/blog"/>
+      </outline>
+      <outline title="Caf` + "\xe9" + `" text="ignored" type="rss" xmlUrl="https://example.com/cafe.xml"/>
+    </outline>
+    <outline text="">
+      <outline text="No folder" xmlUrl="https://example.com/feed.xml"/>
+    </outline>
+  </body>
+</opml>`
+
+	parsed, err := Parse(strings.NewReader(document))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	want := []Subscription{
+		{Title: "Go blog", XMLURL: "https://go.dev/blog/feed.atom", HTMLURL: "https://go.dev/blog", Category: "Tech/Go"},
+		{Title: "Café", XMLURL: "https://example.com/cafe.xml", Category: "Tech"},
+		{Title: "No folder", XMLURL: "https://example.com/feed.xml"},
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("Parse() = %+v, want %+v", parsed, want)
+	}
+}
@@ -0,0 +1,158 @@
+package rss
+
+import (
+	"html"
+	"strings"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type AtomFeed struct {
+	ID       string       `xml:"id"`
+	Title    AtomText     `xml:"title"`
+	Subtitle AtomText     `xml:"subtitle"`
+	Updated  string       `xml:"updated"`
+	Links    []AtomLink   `xml:"link"`
+	Authors  []AtomAuthor `xml:"author"`
+	Entries  []AtomEntry  `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string       `xml:"id"`
+	Title     AtomText     `xml:"title"`
+	Links     []AtomLink   `xml:"link"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Summary   AtomText     `xml:"summary"`
+	Content   AtomText     `xml:"content"`
+	Authors   []AtomAuthor `xml:"author"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type AtomAuthor struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+// AtomText is an Atom text construct. Depending on its type the content is
+// plain text, escaped html or inline xhtml markup.
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	switch t.Type {
+	case "xhtml":
+		return strings.TrimSpace(t.Inner)
+	default:
+		// For "text" and "html" the element content is regular character data,
+		// possibly wrapped in a CDATA section
+		text := strings.TrimSpace(t.Inner)
+		if strings.HasPrefix(text, "<![CDATA[") && strings.HasSuffix(text, "]]>") {
+			return text[len("<![CDATA[") : len(text)-len("]]>")]
+		}
+		return html.UnescapeString(text)
+	}
+}
+
+func (feed AtomFeed) normalize() Feed {
+	normalized := Feed{
+		Title:       feed.Title.String(),
+		Link:        selectAtomLink(feed.Links),
+		Description: feed.Subtitle.String(),
+		Items:       make([]Item, 0, len(feed.Entries)),
+	}
+
+	for _, entry := range feed.Entries {
+		// Posts are identified by their link, entries without one are skipped
+		link := selectAtomEntryLink(entry)
+		if link == "" {
+			continue
+		}
+
+		authors := entry.Authors
+		if len(authors) == 0 {
+			// Entries inherit the feed authors if they do not declare their own
+			authors = feed.Authors
+		}
+
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+
+		content := entry.Content.String()
+		description := entry.Summary.String()
+		if description == "" {
+			description = content
+		}
+
+		normalized.Items = append(normalized.Items, Item{
+			ID:          strings.TrimSpace(entry.ID),
+			Title:       entry.Title.String(),
+			Link:        link,
+			Description: description,
+			Content:     content,
+			Author:      joinAtomAuthors(authors),
+			PubDate:     strings.TrimSpace(published),
+			Updated:     strings.TrimSpace(entry.Updated),
+		})
+	}
+	return normalized
+}
+
+// selectAtomLink picks the link pointing to the html representation of a feed
+// or entry. A link without rel attribute is an "alternate" link per RFC 4287.
+func selectAtomLink(links []AtomLink) string {
+	fallback := ""
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Type == "" || link.Type == "text/html" {
+			return link.Href
+		}
+		if fallback == "" {
+			fallback = link.Href
+		}
+	}
+	return fallback
+}
+
+// selectAtomEntryLink falls back to the first link of any relation and then to
+// the id of the entry if it is a url, e.g. for entries that only have an
+// "enclosure" or "related" link
+func selectAtomEntryLink(entry AtomEntry) string {
+	if link := selectAtomLink(entry.Links); link != "" {
+		return link
+	}
+	for _, link := range entry.Links {
+		if link.Href != "" {
+			return link.Href
+		}
+	}
+	if isWebURL(entry.ID) {
+		return strings.TrimSpace(entry.ID)
+	}
+	return ""
+}
+
+func joinAtomAuthors(authors []AtomAuthor) string {
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		name := strings.TrimSpace(author.Name)
+		if name == "" {
+			name = strings.TrimSpace(author.Email)
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
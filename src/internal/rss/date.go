@@ -0,0 +1,240 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDate = errors.New("invalid date")
+
+var isoDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+// RFC 850 joins the date parts with dashes, e.g. "Sunday, 03-Dec-23"
+var dashedDatePattern = regexp.MustCompile(`^\d{1,2}-[A-Za-z]+-\d{2,4}$`)
+
+// ISO 8601 profiles used by Atom, JSON Feed and dc:date. Seconds, time and
+// zone are optional in the wild.
+var isoDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	// WordPress and Blogger exports separate the offset with a space
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Layouts for RFC 822/1123 style dates after the weekday and the zone have been
+// removed. The zone is appended again as numeric offset if it was present.
+var rfc822DateLayouts = []string{
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 06 15:04:05",
+	"2 Jan 06 15:04",
+	"2 Jan 2006",
+	"2 Jan 06",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 15:04:05 2006",
+	"Jan 2 2006 15:04",
+	"Jan 2 2006",
+}
+
+// Named zones as they appear in feeds. time.Parse does not know the offset
+// of most abbreviations and silently treats them as UTC, so they are mapped
+// to numeric offsets before parsing.
+var namedZoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST":  "-1000",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"MET":  "+0100",
+	"MEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"IST":  "+0530",
+	"SGT":  "+0800",
+	"HKT":  "+0800",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"AWST": "+0800",
+	"ACST": "+0930",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+}
+
+var weekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+var months = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+// ParseDate parses the publication dates found in feeds. It understands RFC
+// 1123/822 dates with or without weekday, seconds and zone, RFC 3339 and
+// the common ISO 8601 variants. Dates without zone are interpreted as UTC.
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("%w: date is empty", ErrInvalidDate)
+	}
+
+	if isoDatePattern.MatchString(value) {
+		normalized := strings.ToUpper(value)
+		for _, layout := range isoDateLayouts {
+			if parsed, err := time.Parse(layout, normalized); err == nil {
+				return parsed, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%w: '%s'", ErrInvalidDate, value)
+	}
+
+	return parseRFC822Date(value)
+}
+
+func parseRFC822Date(value string) (time.Time, error) {
+	// Some publishers append the zone name as comment, e.g. "+0000 (UTC)"
+	if index := strings.Index(value, "("); index > 0 {
+		value = value[:index]
+	}
+
+	fields := []string{}
+	for _, field := range strings.Fields(strings.ReplaceAll(value, ",", " ")) {
+		if dashedDatePattern.MatchString(field) {
+			fields = append(fields, strings.Split(field, "-")...)
+		} else {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) > 0 && isWeekday(fields[0]) {
+		fields = fields[1:]
+	}
+
+	zone := ""
+	remaining := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if offset, ok := parseZone(field); ok && zone == "" {
+			zone = offset
+			continue
+		}
+		if month, ok := normalizeMonth(field); ok {
+			field = month
+		}
+		remaining = append(remaining, field)
+	}
+	if len(remaining) < 3 {
+		return time.Time{}, fmt.Errorf("%w: '%s'", ErrInvalidDate, value)
+	}
+
+	normalized := strings.Join(remaining, " ")
+	for _, layout := range rfc822DateLayouts {
+		input := normalized
+		if zone != "" {
+			layout += " -0700"
+			input += " " + zone
+		}
+		if parsed, err := time.Parse(layout, input); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: '%s'", ErrInvalidDate, value)
+}
+
+func isWeekday(field string) bool {
+	field = strings.ToLower(strings.TrimSuffix(field, "."))
+	if len(field) < 3 {
+		return false
+	}
+	for _, weekday := range weekdays {
+		if strings.HasPrefix(weekday, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeMonth converts full, abbreviated and differently cased month names
+// into the three letter form expected by time.Parse
+func normalizeMonth(field string) (string, bool) {
+	lower := strings.ToLower(strings.TrimSuffix(field, "."))
+	if len(lower) < 3 {
+		return "", false
+	}
+	for _, month := range months {
+		if strings.HasPrefix(lower, month) {
+			// Accept "Sept" and "September" but not arbitrary words like "Mayday"
+			if len(lower) > 3 && !isMonthName(lower) {
+				return "", false
+			}
+			return strings.ToUpper(month[:1]) + month[1:], true
+		}
+	}
+	return "", false
+}
+
+func isMonthName(name string) bool {
+	switch name {
+	case "january", "february", "march", "april", "june", "july", "august",
+		"sept", "september", "october", "november", "december":
+		return true
+	}
+	return false
+}
+
+// parseZone recognizes named zones and numeric offsets like "+0100", "+01:00",
+// "+01" or "GMT+1" and returns the offset in the "-0700" layout format
+func parseZone(field string) (string, bool) {
+	upper := strings.ToUpper(field)
+	if offset, ok := namedZoneOffsets[upper]; ok {
+		return offset, true
+	}
+
+	for _, prefix := range []string{"GMT", "UTC", "UT"} {
+		if strings.HasPrefix(upper, prefix+"+") || strings.HasPrefix(upper, prefix+"-") {
+			upper = upper[len(prefix):]
+			break
+		}
+	}
+	if len(upper) < 2 || (upper[0] != '+' && upper[0] != '-') {
+		return "", false
+	}
+
+	sign := upper[:1]
+	digits := strings.ReplaceAll(upper[1:], ":", "")
+	for _, char := range digits {
+		if char < '0' || char > '9' {
+			return "", false
+		}
+	}
+	switch len(digits) {
+	case 1:
+		return sign + "0" + digits + "00", true
+	case 2:
+		return sign + digits + "00", true
+	case 4:
+		return sign + digits, true
+	}
+	return "", false
+}
@@ -0,0 +1,87 @@
+package rss
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	utc := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+	withoutSeconds := time.Date(2006, time.January, 2, 15, 4, 0, 0, time.UTC)
+	midnight := time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		// ISO 8601 and RFC 3339
+		{"2006-01-02T15:04:05Z", utc},
+		{"2006-01-02T15:04:05.123Z", utc.Add(123 * time.Millisecond)},
+		{"2006-01-02t15:04:05z", utc},
+		{"2006-01-02T17:04:05+02:00", utc},
+		{"2006-01-02T17:04:05+0200", utc},
+		{"2006-01-02T17:04+02:00", withoutSeconds},
+		{"2006-01-02T15:04:05", utc},
+		{"2006-01-02T15:04", withoutSeconds},
+		{"2006-01-02 17:04:05+02:00", utc},
+		{"2006-01-02 17:04:05 +0200", utc},
+		{"2006-01-02 17:04:05 +02:00", utc},
+		{"2006-01-02 10:04:05 -05:00", utc},
+		{"2006-01-02 15:04:05", utc},
+		{"2006-01-02", midnight},
+		// RFC 1123 and RFC 822
+		{"Mon, 02 Jan 2006 15:04:05 GMT", utc},
+		{"Mon, 02 Jan 2006 15:04:05 +0000", utc},
+		{"Mon, 2 Jan 2006 10:04:05 EST", utc},
+		{"Mon, 02 Jan 2006 17:04:05 +02:00", utc},
+		{"Mon, 02 Jan 2006 17:04:05 GMT+2", utc},
+		{"Mon, 02 Jan 2006 15:04:05 +0000 (UTC)", utc},
+		{"Mon, 02 Jan 2006 15:04 GMT", withoutSeconds},
+		{"Mon, 02 Jan 06 15:04:05 GMT", utc},
+		{"Mon, 02 Jan 06 15:04 GMT", withoutSeconds},
+		{"02 Jan 2006 15:04:05 GMT", utc},
+		{"Monday, 02 January 2006 15:04:05 GMT", utc},
+		{"mon, 02 jan 2006 15:04:05 gmt", utc},
+		{"Mon, 02 Jan 2006", midnight},
+		{"Mon, 02 Jan 06", midnight},
+		// RFC 850 and ANSI C
+		{"Monday, 02-Jan-06 15:04:05 GMT", utc},
+		{"Jan 2 2006 15:04:05 GMT", utc},
+		{"Mon Jan 2 15:04:05 2006", utc},
+		{"Jan 2 2006 15:04", withoutSeconds},
+		{"Jan 2 2006", midnight},
+	}
+
+	for _, test := range tests {
+		got, err := ParseDate(test.value)
+		if err != nil {
+			t.Errorf("ParseDate(%q) returned error: %v", test.value, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("ParseDate(%q) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+// Invalid dates return ErrInvalidDate, the aggregator stores their posts
+// without publication date
+func TestParseDateInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"yesterday",
+		"2006-13-45",
+		"2006-01-02 25:00:00",
+		"Mon, 32 Jan 2006 15:04:05 GMT",
+		"Mayday 2 2006",
+		"02 Jan",
+	}
+
+	for _, value := range tests {
+		if got, err := ParseDate(value); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("ParseDate(%q) = %s, %v, want error %v", value, got, err, ErrInvalidDate)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Paths that are probed if a page does not link its feeds
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml", "/rss", "/feed.json"}
+
+var feedMediaTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/rdf+xml":   true,
+	"application/feed+json": true,
+	"application/json":      true,
+}
+
+type DiscoveredFeed struct {
+	URL   string
+	Title string
+	Type  string
+}
+
+// DiscoverFeeds finds the feeds of a website. If the url already points to a
+// feed it is returned as the only result. Otherwise the html page is searched
+// for <link rel="alternate"> elements and, if there are none, common feed paths
+// of the site are probed.
+func DiscoverFeeds(ctx context.Context, pageURL string) ([]DiscoveredFeed, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", "gator")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch page with error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("Failed to fetch page with status: %s", resp.Status)
+	}
+
+	content, err := readResponseBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read response body with error: %w", err)
+	}
+
+	// Redirects may have changed the url the relative links are based on
+	baseURL := resp.Request.URL
+	if feed, err := ParseFeed(content, resp.Header.Get("Content-Type")); err == nil {
+		return []DiscoveredFeed{{URL: baseURL.String(), Title: feed.Title}}, nil
+	}
+
+	feeds, err := findFeedLinks(content, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(feeds) > 0 {
+		return feeds, nil
+	}
+	return probeCommonFeedPaths(ctx, baseURL), nil
+}
+
+func findFeedLinks(content []byte, baseURL *url.URL) ([]DiscoveredFeed, error) {
+	document, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse html page: %w", err)
+	}
+
+	feeds := []DiscoveredFeed{}
+	seen := map[string]bool{}
+	var visit func(node *html.Node)
+	visit = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			switch node.Data {
+			case "base":
+				// <base href> changes how relative links are resolved
+				if href := attribute(node, "href"); href != "" {
+					if resolved, err := baseURL.Parse(href); err == nil {
+						baseURL = resolved
+					}
+				}
+			case "link":
+				feed, ok := parseFeedLink(node, baseURL)
+				if ok && !seen[feed.URL] {
+					seen[feed.URL] = true
+					feeds = append(feeds, feed)
+				}
+			case "body":
+				// Feed links are only allowed in the head
+				return
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(document)
+	return feeds, nil
+}
+
+func parseFeedLink(node *html.Node, baseURL *url.URL) (DiscoveredFeed, bool) {
+	isAlternate := false
+	for _, rel := range strings.Fields(strings.ToLower(attribute(node, "rel"))) {
+		if rel == "alternate" {
+			isAlternate = true
+		}
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(attribute(node, "type")))
+	href := strings.TrimSpace(attribute(node, "href"))
+	if !isAlternate || !feedMediaTypes[mediaType] || href == "" {
+		return DiscoveredFeed{}, false
+	}
+
+	resolved, err := baseURL.Parse(href)
+	if err != nil {
+		return DiscoveredFeed{}, false
+	}
+	return DiscoveredFeed{
+		URL:   resolved.String(),
+		Title: strings.TrimSpace(attribute(node, "title")),
+		Type:  mediaType,
+	}, true
+}
+
+func attribute(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// probeCommonFeedPaths returns the first common feed path of the site that
+// serves a valid feed
+func probeCommonFeedPaths(ctx context.Context, siteURL *url.URL) []DiscoveredFeed {
+	for _, path := range commonFeedPaths {
+		candidate := url.URL{Scheme: siteURL.Scheme, Host: siteURL.Host, Path: path}
+		result, err := FetchFeed(ctx, candidate.String(), CacheValidators{})
+		if err != nil || result.Feed == nil {
+			continue
+		}
+		return []DiscoveredFeed{{URL: candidate.String(), Title: result.Feed.Title}}
+	}
+	return []DiscoveredFeed{}
+}
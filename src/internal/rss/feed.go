@@ -0,0 +1,37 @@
+package rss
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Feed is the format independent representation of a fetched feed. Every
+// supported wire format (RSS 2.0, RSS 1.0, Atom, JSON Feed) is normalized into
+// this shape.
+type Feed struct {
+	Title       string
+	Link        string
+	Description string
+	Hints       UpdateHints
+	Items       []Item
+}
+
+type Item struct {
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	Author      string
+	// PubDate and Updated are kept as they appear in the document because
+	// the date formats differ between feed formats and publishers. Use ParseDate
+	// to convert them.
+	PubDate string
+	Updated string
+}
+
+// isWebURL reports whether an item id can be used as the link of the item
+func isWebURL(value string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
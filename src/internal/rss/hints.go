@@ -0,0 +1,112 @@
+package rss
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UpdateHints are the publishers suggestions on how often a feed should be
+// polled. Zero values mean that the feed does not provide the hint.
+type UpdateHints struct {
+	// TTL is the RSS 2.0 <ttl> element
+	TTL time.Duration
+	// UpdatePeriod is derived from <sy:updatePeriod> and <sy:updateFrequency>
+	UpdatePeriod time.Duration
+	// SkipHours and SkipDays are in GMT as defined by RSS 2.0
+	SkipHours []int
+	SkipDays  []time.Weekday
+}
+
+// syndication holds the elements of the RSS syndication module
+type syndication struct {
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+func (s syndication) period() time.Duration {
+	var period time.Duration
+	switch strings.ToLower(strings.TrimSpace(s.UpdatePeriod)) {
+	case "":
+		return 0
+	case "hourly":
+		period = time.Hour
+	case "daily":
+		period = 24 * time.Hour
+	case "weekly":
+		period = 7 * 24 * time.Hour
+	case "monthly":
+		period = 30 * 24 * time.Hour
+	case "yearly":
+		period = 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+
+	// The frequency is the number of updates within the period
+	frequency, err := strconv.Atoi(strings.TrimSpace(s.UpdateFrequency))
+	if err != nil || frequency < 1 {
+		frequency = 1
+	}
+	return period / time.Duration(frequency)
+}
+
+func parseTTL(ttl string) time.Duration {
+	minutes, err := strconv.Atoi(strings.TrimSpace(ttl))
+	if err != nil || minutes < 1 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func parseSkipHours(hours []string) []int {
+	parsed := []int{}
+	for _, hour := range hours {
+		value, err := strconv.Atoi(strings.TrimSpace(hour))
+		// Some publishers use 24 for midnight
+		if err == nil && value >= 0 && value <= 24 {
+			parsed = append(parsed, value%24)
+		}
+	}
+	return parsed
+}
+
+func parseSkipDays(days []string) []time.Weekday {
+	parsed := []time.Weekday{}
+	for _, day := range days {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+				parsed = append(parsed, weekday)
+			}
+		}
+	}
+	return parsed
+}
+
+// readFreshness returns how long a response may be cached according to the
+// Cache-Control and Expires headers
+func readFreshness(header http.Header, now time.Time) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || seconds < 0 {
+				return 0
+			}
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil || !expiresAt.After(now) {
+			return 0
+		}
+		return expiresAt.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package rss
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Author      *JSONFeedAuthor  `json:"author"`
+	Authors     []JSONFeedAuthor `json:"authors"`
+	Items       []JSONFeedItem   `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            JSONFeedID       `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Author        *JSONFeedAuthor  `json:"author"`
+	Authors       []JSONFeedAuthor `json:"authors"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// JSONFeedID is the id of a JSON Feed item. The spec requires a string but a
+// lot of publishers emit plain numbers, so both are accepted.
+type JSONFeedID string
+
+func (id *JSONFeedID) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*id = JSONFeedID(text)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*id = JSONFeedID(number.String())
+	return nil
+}
+
+func (feed JSONFeed) normalize() Feed {
+	normalized := Feed{
+		Title:       feed.Title,
+		Link:        feed.HomePageURL,
+		Description: feed.Description,
+		Items:       make([]Item, 0, len(feed.Items)),
+	}
+
+	for _, item := range feed.Items {
+		// Only the id is required. Posts are identified by their link, so
+		// items without a url are skipped unless the id is one.
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		if link == "" && isWebURL(string(item.ID)) {
+			link = strings.TrimSpace(string(item.ID))
+		}
+		if link == "" {
+			continue
+		}
+
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+		description := item.Summary
+		if description == "" {
+			description = content
+		}
+
+		// Version 1.1 replaced "author" with "authors". Items without authors
+		// inherit the ones of the feed
+		authors := collectJSONFeedAuthors(item.Author, item.Authors)
+		if len(authors) == 0 {
+			authors = collectJSONFeedAuthors(feed.Author, feed.Authors)
+		}
+
+		published := item.DatePublished
+		if published == "" {
+			published = item.DateModified
+		}
+
+		normalized.Items = append(normalized.Items, Item{
+			ID:          string(item.ID),
+			Title:       item.Title,
+			Link:        link,
+			Description: description,
+			Content:     content,
+			Author:      strings.Join(authors, ", "),
+			PubDate:     strings.TrimSpace(published),
+			Updated:     strings.TrimSpace(item.DateModified),
+		})
+	}
+	return normalized
+}
+
+func collectJSONFeedAuthors(author *JSONFeedAuthor, authors []JSONFeedAuthor) []string {
+	names := []string{}
+	if author != nil && author.Name != "" {
+		names = append(names, author.Name)
+	}
+	for _, a := range authors {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	return mediaType == "application/feed+json" || mediaType == "application/json"
+}
+
+// looksLikeJSON reports whether the payload starts like a json object. It is
+// used when the server does not send a useful Content-Type header.
+func looksLikeJSON(content []byte) bool {
+	trimmed := strings.TrimLeft(string(content), " \t\r\n\ufeff")
+	return strings.HasPrefix(trimmed, "{")
+}
@@ -0,0 +1,65 @@
+package rss
+
+import "strings"
+
+const (
+	rdfNamespace        = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	dublinCoreNamespace = "http://purl.org/dc/elements/1.1/"
+)
+
+// RDFFeed is an RSS 1.0 (RDF Site Summary) document. Unlike RSS 2.0 the items
+// are siblings of the channel element instead of its children.
+type RDFFeed struct {
+	Channel struct {
+		Title       string  `xml:"title"`
+		Link        RSSLink `xml:"link"`
+		Description string  `xml:"description"`
+		syndication
+	} `xml:"channel"`
+	Item []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string  `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string  `xml:"title"`
+	Link        RSSLink `xml:"link"`
+	Description string  `xml:"description"`
+	Date        string  `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string  `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+func (feed RDFFeed) normalize() Feed {
+	normalized := Feed{
+		Title:       strings.TrimSpace(feed.Channel.Title),
+		Link:        string(feed.Channel.Link),
+		Description: strings.TrimSpace(feed.Channel.Description),
+		Hints:       UpdateHints{UpdatePeriod: feed.Channel.period()},
+		Items:       make([]Item, 0, len(feed.Item)),
+	}
+
+	for _, item := range feed.Item {
+		// Posts are identified by their link, items without one are skipped
+		// unless their rdf:about is a url
+		link := string(item.Link)
+		if link == "" && isWebURL(item.About) {
+			link = strings.TrimSpace(item.About)
+		}
+		if link == "" {
+			continue
+		}
+		id := item.About
+		if id == "" {
+			id = link
+		}
+
+		normalized.Items = append(normalized.Items, Item{
+			ID:          id,
+			Title:       strings.TrimSpace(item.Title),
+			Link:        link,
+			Description: strings.TrimSpace(item.Description),
+			Author:      strings.TrimSpace(item.Creator),
+			PubDate:     strings.TrimSpace(item.Date),
+		})
+	}
+	return normalized
+}
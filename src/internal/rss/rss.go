@@ -1,53 +1,283 @@
 package rss
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1DIce/gator/internal/metrics"
 )
 
 type RSSFeed struct {
 	Channel struct {
 		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
+		Link        RSSLink   `xml:"link"`
 		Description string    `xml:"description"`
+		TTL         string    `xml:"ttl"`
+		SkipHours   []string  `xml:"skipHours>hour"`
+		SkipDays    []string  `xml:"skipDays>day"`
 		Item        []RSSItem `xml:"item"`
+		syndication
 	} `xml:"channel"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string  `xml:"title"`
+	Link        RSSLink `xml:"link"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate"`
+	GUID        RSSGUID `xml:"guid"`
+	Author      string  `xml:"author"`
+}
+
+// RSSGUID identifies an item. Unless isPermaLink is "false" it is also the
+// url of the item.
+type RSSGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+// link returns the guid if it can be used as the link of the item
+func (guid RSSGUID) link() string {
+	if strings.TrimSpace(guid.IsPermaLink) == "false" || !isWebURL(guid.Value) {
+		return ""
+	}
+	return strings.TrimSpace(guid.Value)
+}
+
+// RSSLink is the <link> element of RSS 2.0 and RSS 1.0 documents. Many feeds
+// also declare their own url with <atom:link rel="self" href="..."/>, which
+// matches the same field but has no text. Those links are skipped, so they
+// do not replace the link to the website.
+type RSSLink string
+
+func (link *RSSLink) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	if start.Name.Space == atomNamespace {
+		return decoder.Skip()
+	}
+	var value string
+	if err := decoder.DecodeElement(&value, &start); err != nil {
+		return err
+	}
+	*link = RSSLink(strings.TrimSpace(value))
+	return nil
+}
+
+var ErrUnsupportedFormat = errors.New("unsupported feed format")
+
+var ErrResponseTooLarge = errors.New("response is too large")
+
+// maxResponseSize limits the feeds and pages that are read into memory. The
+// urls are supplied by users, so a server could send an endless response.
+const maxResponseSize = 8 << 20
+
+// CacheValidators are the validators of a previous response. They are sent
+// with the next request so the server can answer with 304 Not Modified.
+type CacheValidators struct {
+	ETag         string
+	LastModified string
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+type FetchResult struct {
+	// Feed is nil if the server reported that the feed did not change
+	Feed        *Feed
+	NotModified bool
+	Validators  CacheValidators
+	// FreshFor is how long the response may be cached according to the
+	// Cache-Control and Expires headers
+	FreshFor time.Duration
+}
+
+func FetchFeed(ctx context.Context, feedURL string, validators CacheValidators) (*FetchResult, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Add("User-Agent", "gator")
+	req.Header.Add("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	if validators.ETag != "" {
+		req.Header.Add("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Add("If-Modified-Since", validators.LastModified)
+	}
+
+	start := time.Now()
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		observeFetch(start, "error")
 		return nil, fmt.Errorf("Failed to fetch feed with error: %v", err)
 	}
 	defer resp.Body.Close()
-	content, err := io.ReadAll(resp.Body)
+	code := strconv.Itoa(resp.StatusCode)
+
+	if resp.StatusCode == http.StatusNotModified {
+		observeFetch(start, code)
+		// Servers are allowed to omit the validators on a 304 response
+		responseValidators := readCacheValidators(resp.Header)
+		if responseValidators.ETag == "" {
+			responseValidators.ETag = validators.ETag
+		}
+		if responseValidators.LastModified == "" {
+			responseValidators.LastModified = validators.LastModified
+		}
+		return &FetchResult{
+			NotModified: true,
+			Validators:  responseValidators,
+			FreshFor:    readFreshness(resp.Header, time.Now()),
+		}, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		observeFetch(start, code)
+		return nil, fmt.Errorf("Failed to fetch feed with status: %s", resp.Status)
+	}
+
+	content, err := readResponseBody(resp.Body)
+	metrics.BytesDownloaded.Add(float64(len(content)))
+	observeFetch(start, code)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to read request body with error: %v", err)
+		return nil, fmt.Errorf("Failed to read request body with error: %w", err)
 	}
-	var feed RSSFeed
-	if err := xml.Unmarshal(content, &feed); err != nil {
-		return nil, fmt.Errorf("Failed to Unmarshal request body with error: %v", err)
+
+	feed, err := ParseFeed(content, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
 	}
+	return &FetchResult{
+		Feed:       &feed,
+		Validators: readCacheValidators(resp.Header),
+		FreshFor:   readFreshness(resp.Header, time.Now()),
+	}, nil
+}
 
-	feed = unescapeFeedFields(feed)
-	return &feed, nil
+// readResponseBody reads at most maxResponseSize bytes of a response
+func readResponseBody(body io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(body, maxResponseSize+1))
+	if err != nil {
+		return content, err
+	}
+	if len(content) > maxResponseSize {
+		return content, fmt.Errorf("%w, it exceeds %d MiB", ErrResponseTooLarge, maxResponseSize>>20)
+	}
+	return content, nil
+}
+
+// observeFetch records the duration of a request, code is the status code of
+// the response or "error" if none was received
+func observeFetch(start time.Time, code string) {
+	metrics.FetchDuration.WithLabelValues(code).Observe(time.Since(start).Seconds())
+}
+
+func readCacheValidators(header http.Header) CacheValidators {
+	return CacheValidators{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
+
+// ParseFeed detects the format of the given document and converts it into a Feed.
+// The content type is optional and only used to recognize JSON feeds.
+func ParseFeed(content []byte, contentType string) (Feed, error) {
+	if isJSONContentType(contentType) || looksLikeJSON(content) {
+		return parseJSONFeed(content)
+	}
+
+	root, err := detectRootElement(content)
+	if err != nil {
+		return Feed{}, fmt.Errorf("Failed to Unmarshal request body with error: %v", err)
+	}
+
+	switch {
+	case root.Local == "rss":
+		var feed RSSFeed
+		if err := xml.Unmarshal(content, &feed); err != nil {
+			return Feed{}, fmt.Errorf("Failed to Unmarshal request body with error: %v", err)
+		}
+		return unescapeFeedFields(feed).normalize(), nil
+	case root.Local == "RDF" && root.Space == rdfNamespace:
+		var feed RDFFeed
+		if err := xml.Unmarshal(content, &feed); err != nil {
+			return Feed{}, fmt.Errorf("Failed to Unmarshal request body with error: %v", err)
+		}
+		return unescapeRDFFeedFields(feed).normalize(), nil
+	case root.Local == "feed" && (root.Space == atomNamespace || root.Space == ""):
+		var feed AtomFeed
+		if err := xml.Unmarshal(content, &feed); err != nil {
+			return Feed{}, fmt.Errorf("Failed to Unmarshal request body with error: %v", err)
+		}
+		return feed.normalize(), nil
+	default:
+		return Feed{}, fmt.Errorf("%w: unexpected root element <%s>", ErrUnsupportedFormat, root.Local)
+	}
+}
+
+func parseJSONFeed(content []byte) (Feed, error) {
+	var feed JSONFeed
+	if err := json.Unmarshal(content, &feed); err != nil {
+		return Feed{}, fmt.Errorf("Failed to Unmarshal request body with error: %v", err)
+	}
+	if !strings.HasPrefix(feed.Version, jsonFeedVersionPrefix) {
+		return Feed{}, fmt.Errorf("%w: unknown JSON Feed version '%s'", ErrUnsupportedFormat, feed.Version)
+	}
+	return feed.normalize(), nil
+}
+
+func detectRootElement(content []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			return element.Name, nil
+		}
+	}
+}
+
+func (feed RSSFeed) normalize() Feed {
+	normalized := Feed{
+		Title:       feed.Channel.Title,
+		Link:        string(feed.Channel.Link),
+		Description: feed.Channel.Description,
+		Hints: UpdateHints{
+			TTL:          parseTTL(feed.Channel.TTL),
+			UpdatePeriod: feed.Channel.period(),
+			SkipHours:    parseSkipHours(feed.Channel.SkipHours),
+			SkipDays:     parseSkipDays(feed.Channel.SkipDays),
+		},
+		Items: make([]Item, 0, len(feed.Channel.Item)),
+	}
+	for _, item := range feed.Channel.Item {
+		// Posts are identified by their link, items without one are skipped
+		link := string(item.Link)
+		if link == "" {
+			link = item.GUID.link()
+		}
+		if link == "" {
+			continue
+		}
+
+		normalized.Items = append(normalized.Items, Item{
+			ID:          item.GUID.Value,
+			Title:       item.Title,
+			Link:        link,
+			Description: item.Description,
+			Author:      item.Author,
+			PubDate:     item.PubDate,
+		})
+	}
+	return normalized
 }
 
 func unescapeFeedFields(feed RSSFeed) RSSFeed {
@@ -59,3 +289,13 @@ func unescapeFeedFields(feed RSSFeed) RSSFeed {
 	}
 	return feed
 }
+
+func unescapeRDFFeedFields(feed RDFFeed) RDFFeed {
+	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
+	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
+	for i := 0; i < len(feed.Item); i++ {
+		feed.Item[i].Title = html.UnescapeString(feed.Item[i].Title)
+		feed.Item[i].Description = html.UnescapeString(feed.Item[i].Description)
+	}
+	return feed
+}
@@ -0,0 +1,230 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		file        string
+		contentType string
+		want        Feed
+	}{
+		{
+			file:        "rss.xml",
+			contentType: "application/rss+xml",
+			want: Feed{
+				Title:       "Example & Co",
+				Link:        "https://example.com/",
+				Description: "Posts about examples",
+				Hints: UpdateHints{
+					TTL:          time.Hour,
+					UpdatePeriod: 6 * time.Hour,
+					SkipHours:    []int{0, 1},
+					SkipDays:     []time.Weekday{},
+				},
+				Items: []Item{
+					{
+						ID:          "https://example.com/?p=1",
+						Title:       "First <post>",
+						Link:        "https://example.com/first",
+						Description: "Hello & welcome",
+						Author:      "jane@example.com (Jane)",
+						PubDate:     "Mon, 02 Jan 2006 15:04:05 +0000",
+					},
+					{
+						ID:      "https://example.com/?p=2",
+						Title:   "Second post",
+						Link:    "https://example.com/second",
+						PubDate: "Tue, 03 Jan 2006 15:04:05 GMT",
+					},
+					{
+						ID:    "https://example.com/third",
+						Title: "Permalink guid",
+						Link:  "https://example.com/third",
+					},
+					{
+						ID:    "https://example.com/fourth",
+						Title: "Guid without attribute",
+						Link:  "https://example.com/fourth",
+					},
+				},
+			},
+		},
+		{
+			file:        "atom.xml",
+			contentType: "application/atom+xml",
+			want: Feed{
+				Title:       "Example Atom",
+				Link:        "https://example.com/",
+				Description: "A <b>bold</b> feed",
+				Items: []Item{
+					{
+						ID:          "tag:example.com,2006:1",
+						Title:       "Alternate link",
+						Link:        "https://example.com/1",
+						Description: "Summary of the first entry",
+						Content:     "<p>Content</p>",
+						Author:      "Jane",
+						PubDate:     "2006-01-02T15:04:05Z",
+						Updated:     "2006-01-03T15:04:05Z",
+					},
+					{
+						ID:          "tag:example.com,2006:2",
+						Title:       "Related link only",
+						Link:        "https://example.org/2",
+						Description: `<div xmlns="http://www.w3.org/1999/xhtml"><p>Inline</p></div>`,
+						Content:     `<div xmlns="http://www.w3.org/1999/xhtml"><p>Inline</p></div>`,
+						Author:      "John",
+						PubDate:     "2006-01-04T15:04:05Z",
+						Updated:     "2006-01-04T15:04:05Z",
+					},
+					{
+						ID:      "https://example.com/3",
+						Title:   "Id is the link",
+						Link:    "https://example.com/3",
+						Author:  "Jane",
+						PubDate: "2006-01-05T15:04:05Z",
+						Updated: "2006-01-05T15:04:05Z",
+					},
+				},
+			},
+		},
+		{
+			file:        "rdf.xml",
+			contentType: "application/rdf+xml",
+			want: Feed{
+				Title:       "Example RDF",
+				Link:        "https://example.com/",
+				Description: "An RSS 1.0 feed",
+				Hints:       UpdateHints{UpdatePeriod: time.Hour},
+				Items: []Item{
+					{
+						ID:          "https://example.com/rdf/1",
+						Title:       "First item",
+						Link:        "https://example.com/rdf/1",
+						Description: "First description",
+						Author:      "Jane",
+						PubDate:     "2006-01-02T15:04:05+01:00",
+					},
+					{
+						ID:    "https://example.com/rdf/2",
+						Title: "Second item",
+						Link:  "https://example.com/rdf/2",
+					},
+					{
+						ID:    "https://example.com/rdf/3",
+						Title: "Without link",
+						Link:  "https://example.com/rdf/3",
+					},
+				},
+			},
+		},
+		{
+			file:        "feed.json",
+			contentType: "application/feed+json",
+			want: Feed{
+				Title:       "Example JSON",
+				Link:        "https://example.com/",
+				Description: "A JSON feed",
+				Items: []Item{
+					{
+						ID:          "1",
+						Title:       "First item",
+						Link:        "https://example.com/json/1",
+						Description: "Short",
+						Content:     "<p>Hello</p>",
+						Author:      "Jane",
+						PubDate:     "2006-01-02T15:04:05Z",
+						Updated:     "2006-01-03T15:04:05Z",
+					},
+					{
+						ID:          "2",
+						Link:        "https://example.org/json/2",
+						Description: "Plain text",
+						Content:     "Plain text",
+						Author:      "John",
+						PubDate:     "2006-01-04T15:04:05Z",
+						Updated:     "2006-01-04T15:04:05Z",
+					},
+					{
+						ID:      "https://example.com/json/3",
+						Title:   "Id is the link",
+						Link:    "https://example.com/json/3",
+						Author:  "Jane",
+						PubDate: "2006-01-05T15:04:05Z",
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The format is detected from the content, the content type is
+			// only needed for JSON feeds
+			for _, contentType := range []string{test.contentType, ""} {
+				got, err := ParseFeed(content, contentType)
+				if err != nil {
+					t.Fatalf("ParseFeed(%q) returned error: %v", contentType, err)
+				}
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("ParseFeed(%q) = %+v, want %+v", contentType, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFeedUnsupportedFormat(t *testing.T) {
+	tests := []string{
+		`<html><body>Not a feed</body></html>`,
+		`{"version": "https://example.com/other", "items": []}`,
+	}
+	for _, content := range tests {
+		if _, err := ParseFeed([]byte(content), ""); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("ParseFeed(%q) returned error %v, want %v", content, err, ErrUnsupportedFormat)
+		}
+	}
+}
+
+func TestFetchLargeResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/rss+xml")
+		io.WriteString(writer, `<rss version="2.0"><channel><description>`)
+		// The description never ends, like an endless response
+		io.CopyN(writer, zeros{}, maxResponseSize)
+	}))
+	defer server.Close()
+
+	if _, err := FetchFeed(context.Background(), server.URL, CacheValidators{}); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("FetchFeed() returned error %v, want %v", err, ErrResponseTooLarge)
+	}
+	if _, err := DiscoverFeeds(context.Background(), server.URL); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("DiscoverFeeds() returned error %v, want %v", err, ErrResponseTooLarge)
+	}
+}
+
+// zeros is an endless reader of "0" characters
+type zeros struct{}
+
+func (zeros) Read(buffer []byte) (int, error) {
+	for i := range buffer {
+		buffer[i] = '0'
+	}
+	return len(buffer), nil
+}
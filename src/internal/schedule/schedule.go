@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"slices"
+	"time"
+
+	"github.com/1DIce/gator/internal/rss"
+)
+
+const (
+	// DefaultInterval is used for feeds without any hints or post history
+	DefaultInterval = time.Hour
+	MinInterval     = 5 * time.Minute
+	MaxInterval     = 24 * time.Hour
+)
+
+type Input struct {
+	// Override is the interval configured by a user. It replaces the observed
+	// posting frequency and MaxInterval, the publisher hints still apply.
+	Override time.Duration
+	Hints    rss.UpdateHints
+	// FreshFor is the cache lifetime announced by the http response
+	FreshFor time.Duration
+	// PublicationDates of the most recent posts of the feed
+	PublicationDates []time.Time
+}
+
+// NextFetch computes when a feed should be fetched again. The interval is
+// based on the override or the observed posting frequency, but never shorter
+// than what the publisher asks for through ttl, sy:updatePeriod or http
+// caching headers.
+// The result is moved out of the hours and days the publisher wants to skip.
+func NextFetch(now time.Time, input Input) time.Time {
+	interval := input.Override
+	if interval == 0 {
+		interval = ObservedInterval(input.PublicationDates)
+		if interval == 0 {
+			interval = DefaultInterval
+		}
+	}
+	interval = max(interval, input.Hints.TTL, input.Hints.UpdatePeriod, input.FreshFor, MinInterval)
+	if input.Override == 0 {
+		interval = min(interval, MaxInterval)
+	}
+
+	return skipExcludedTimes(now.Add(interval), input.Hints)
+}
+
+// ObservedInterval returns the median time between the given publication
+// dates or 0 if there are not enough dates to make a guess
+func ObservedInterval(dates []time.Time) time.Duration {
+	if len(dates) < 2 {
+		return 0
+	}
+
+	sorted := slices.Clone(dates)
+	slices.SortFunc(sorted, func(a, b time.Time) int { return b.Compare(a) })
+
+	gaps := make([]time.Duration, 0, len(sorted)-1)
+	for i := 1; i < len(sorted); i++ {
+		gaps = append(gaps, sorted[i-1].Sub(sorted[i]))
+	}
+	slices.Sort(gaps)
+	return gaps[len(gaps)/2]
+}
+
+func skipExcludedTimes(next time.Time, hints rss.UpdateHints) time.Time {
+	if len(hints.SkipHours) == 0 && len(hints.SkipDays) == 0 {
+		return next
+	}
+
+	// A week has 168 hours, if all of them are skipped the hints are ignored
+	candidate := next
+	for range 7 * 24 {
+		utc := candidate.UTC()
+		if !slices.Contains(hints.SkipHours, utc.Hour()) && !slices.Contains(hints.SkipDays, utc.Weekday()) {
+			return candidate
+		}
+		candidate = utc.Truncate(time.Hour).Add(time.Hour).In(next.Location())
+	}
+	return next
+}
+
+// Backoff returns how long to wait before retrying a feed that failed the
+// given number of times in a row. The delay doubles with every failure.
+func Backoff(consecutiveFailures int) time.Duration {
+	delay := MinInterval
+	for i := 1; i < consecutiveFailures && delay < MaxInterval; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxInterval)
+}
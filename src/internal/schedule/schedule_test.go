@@ -0,0 +1,148 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1DIce/gator/internal/rss"
+)
+
+// now is a Monday at noon UTC
+var now = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// hoursApart returns publication dates before now that are the given number
+// of hours apart
+func hoursApart(hours ...int) []time.Time {
+	dates := []time.Time{now}
+	for _, gap := range hours {
+		dates = append(dates, dates[len(dates)-1].Add(-time.Duration(gap)*time.Hour))
+	}
+	return dates
+}
+
+func TestNextFetch(t *testing.T) {
+	tests := []struct {
+		name  string
+		input Input
+		want  time.Duration
+	}{
+		{"without inputs", Input{}, DefaultInterval},
+		{"observed frequency", Input{PublicationDates: hoursApart(2, 2, 2)}, 2 * time.Hour},
+		{"frequent posts", Input{PublicationDates: []time.Time{now, now.Add(-time.Minute), now.Add(-2 * time.Minute)}}, MinInterval},
+		{"rare posts", Input{PublicationDates: hoursApart(72, 72)}, MaxInterval},
+		{"ttl", Input{Hints: rss.UpdateHints{TTL: 3 * time.Hour}, PublicationDates: hoursApart(2, 2)}, 3 * time.Hour},
+		{"update period", Input{Hints: rss.UpdateHints{UpdatePeriod: 6 * time.Hour}}, 6 * time.Hour},
+		{"http freshness", Input{FreshFor: 90 * time.Minute}, 90 * time.Minute},
+		{"hints above the maximum", Input{Hints: rss.UpdateHints{TTL: 48 * time.Hour}}, MaxInterval},
+		{"override", Input{Override: 30 * time.Minute, PublicationDates: hoursApart(2, 2)}, 30 * time.Minute},
+		{"override below the minimum", Input{Override: time.Minute}, MinInterval},
+		{"override below the ttl", Input{Override: 30 * time.Minute, Hints: rss.UpdateHints{TTL: time.Hour}}, time.Hour},
+		{"override above the maximum", Input{Override: 7 * 24 * time.Hour}, 7 * 24 * time.Hour},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NextFetch(now, test.input).Sub(now)
+			if got != test.want {
+				t.Errorf("NextFetch() is %s after now, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNextFetchSkipsExcludedTimes(t *testing.T) {
+	tests := []struct {
+		name     string
+		override time.Duration
+		hints    rss.UpdateHints
+		want     time.Time
+	}{
+		{
+			name:  "skip hours",
+			hints: rss.UpdateHints{SkipHours: []int{13, 14}},
+			want:  time.Date(2024, time.January, 1, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "skip days",
+			hints: rss.UpdateHints{SkipDays: []time.Weekday{time.Monday}},
+			want:  time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "override respects skip hours",
+			override: 10 * time.Minute,
+			hints:    rss.UpdateHints{SkipHours: []int{12}},
+			want:     time.Date(2024, time.January, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "not skipped",
+			hints: rss.UpdateHints{SkipHours: []int{3}, SkipDays: []time.Weekday{time.Sunday}},
+			want:  now.Add(DefaultInterval),
+		},
+		{
+			name:  "every hour skipped",
+			hints: rss.UpdateHints{SkipHours: allHours()},
+			want:  now.Add(DefaultInterval),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NextFetch(now, Input{Override: test.override, Hints: test.hints})
+			if !got.Equal(test.want) {
+				t.Errorf("NextFetch() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func allHours() []int {
+	hours := make([]int, 24)
+	for hour := range hours {
+		hours[hour] = hour
+	}
+	return hours
+}
+
+func TestObservedInterval(t *testing.T) {
+	tests := []struct {
+		name  string
+		dates []time.Time
+		want  time.Duration
+	}{
+		{"no dates", nil, 0},
+		{"single date", hoursApart(), 0},
+		{"two dates", hoursApart(5), 5 * time.Hour},
+		{"median of gaps", hoursApart(1, 10, 2, 3, 1), 2 * time.Hour},
+		{"unsorted", []time.Time{now.Add(-4 * time.Hour), now, now.Add(-2 * time.Hour)}, 2 * time.Hour},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ObservedInterval(test.dates); got != test.want {
+				t.Errorf("ObservedInterval() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, MinInterval},
+		{1, MinInterval},
+		{2, 2 * MinInterval},
+		{3, 4 * MinInterval},
+		{5, 16 * MinInterval},
+		{9, 256 * MinInterval},
+		{10, MaxInterval},
+		{100, MaxInterval},
+	}
+
+	for _, test := range tests {
+		if got := Backoff(test.failures); got != test.want {
+			t.Errorf("Backoff(%d) = %s, want %s", test.failures, got, test.want)
+		}
+	}
+}
@@ -4,26 +4,28 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"slices"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/1DIce/gator/internal/config"
 	"github.com/1DIce/gator/internal/database"
 	"github.com/1DIce/gator/internal/rss"
+	"github.com/1DIce/gator/internal/schedule"
 	"github.com/google/uuid"
 
 	// Importing postgresql driver. It is a dependency of sqlc
-	pg "github.com/lib/pq"
+	_ "github.com/lib/pq"
 )
 
 type State struct {
 	config *config.Config
+	// conn is the connection pool of db, it is replaced if the config is reloaded
+	conn   *sql.DB
 	db     *database.Queries
+	output outputFormat
 }
 
 type cliCommand struct {
@@ -32,10 +34,18 @@ type cliCommand struct {
 }
 
 func middlewareLoggedIn(handler func(state *State, arguments []string, user database.User) error) func(*State, []string) error {
+	return middlewareScope(scopeManage, handler)
+}
+
+// middlewareScope requires credentials with at least the given scope, see accessScope
+func middlewareScope(required accessScope, handler func(state *State, arguments []string, user database.User) error) func(*State, []string) error {
 	return func(state *State, arguments []string) error {
-		user, err := state.db.GetUser(context.Background(), state.config.CurrentUserName)
+		user, scope, err := currentUser(context.Background(), state)
 		if err != nil {
-			return fmt.Errorf("User with name '%s' does not exist", state.config.CurrentUserName)
+			return err
+		}
+		if !scope.allows(required) {
+			return permissionError(fmt.Sprintf("The command requires the '%s' scope, the credentials only have '%s'", required, scope))
 		}
 		return handler(state, arguments, user)
 	}
@@ -49,12 +59,34 @@ func loginCommand(state *State, arguments []string) error {
 		return fmt.Errorf("Too many arguments! 'login' command expects a single argument")
 	}
 	userName := arguments[0]
-	if _, err := state.db.GetUser(context.Background(), userName); err != nil {
+	user, err := state.db.GetUser(context.Background(), userName)
+	if err != nil {
 		return fmt.Errorf("User with name '%s' does not exist", userName)
 	}
 
-	state.config.CurrentUserName = userName
-	config.Write(*state.config)
+	_, err = state.db.GetUserPasswordHash(context.Background(), user.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Users registered before passwords were introduced, and users whose
+		// password was reset, choose one with a token of 'reset-password'
+		if err := choosePasswordWithToken(context.Background(), state, user); err != nil {
+			return err
+		}
+	case err != nil:
+		return fmt.Errorf("Failed to get password of '%s': %w", userName, err)
+	default:
+		password, err := readPassword("Password: ")
+		if err != nil {
+			return err
+		}
+		if err := checkPassword(context.Background(), state, user, password); err != nil {
+			return err
+		}
+	}
+
+	if err := startSession(context.Background(), state, user); err != nil {
+		return err
+	}
 	fmt.Printf("User has been successfully set to '%s'\n", userName)
 	return nil
 }
@@ -68,141 +100,68 @@ func registerUserCommand(state *State, arguments []string) error {
 	}
 
 	username := arguments[0]
+	password, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+	// The password is validated before the user is created
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
 
 	now := time.Now()
 	user, err := state.db.CreateUser(context.Background(), database.CreateUserParams{
-		Name:      username,
-		ID:        uuid.New(),
-		CreatedAt: now,
-		UpdatedAt: now,
+		Name:         username,
+		ID:           uuid.New(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		AdminIfFirst: true,
 	})
 	if err != nil {
 		return fmt.Errorf("The user does already exist")
 	}
+	if err := state.db.SetUserPassword(context.Background(), database.SetUserPasswordParams{
+		UserID:       user.ID,
+		PasswordHash: passwordHash,
+		CreatedAt:    now,
+	}); err != nil {
+		return fmt.Errorf("Failed to set password: %w", err)
+	}
 
-	state.config.CurrentUserName = user.Name
-	config.Write(*state.config)
+	if err := startSession(context.Background(), state, user); err != nil {
+		return err
+	}
 	fmt.Printf("User '%s' was created\n", user.Name)
 	return nil
 }
 
-func listUsersCommand(state *State, arguments []string) error {
+func listUsersCommand(state *State, arguments []string, user database.User) error {
 	users, err := state.db.GetUsers(context.Background())
 	if err != nil {
 		return fmt.Errorf("Failed to fetch the list of users")
 	}
-
-	for _, user := range users {
-		if user.Name == state.config.CurrentUserName {
-			fmt.Printf("* %s (current)\n", user.Name)
-		} else {
-			fmt.Printf("* %s\n", user.Name)
-		}
-	}
-	return nil
-}
-
-func resetUsersCommand(state *State, arguments []string) error {
-	err := state.db.DeleteAllUsers(context.Background())
-	if err != nil {
-		return fmt.Errorf("Failed to delete users with error: %v", err)
-	}
-	return nil
-}
-
-func aggregateFeedsCommand(state *State, arguments []string) error {
-	if len(arguments) == 0 || arguments[0] == "" {
-		return fmt.Errorf("Timer input is missing")
-	}
-	if len(arguments) > 1 {
-		return fmt.Errorf("Too many arguments! 'agg' command expects a single argument")
-	}
-
-	timeBetweenRequests, err := time.ParseDuration(arguments[0])
-	if err != nil {
-		return fmt.Errorf("timer format is invalid: %w", err)
-	}
-	fmt.Printf("Collecting feeds every %s\n\n", timeBetweenRequests.String())
-
-	ticker := time.NewTicker(timeBetweenRequests)
-	for ; ; <-ticker.C {
-		scrapeFeeds(state)
-		fmt.Println("")
-		fmt.Printf("Waiting %s to fetch the next...\n\n", timeBetweenRequests.String())
-	}
-}
-
-func scrapeFeeds(state *State) error {
-	feed, err := state.db.GetNextFeedToFetch(context.Background())
-	if err != nil {
-		return fmt.Errorf("Failed to get next feed to fetch: %w", err)
+	if state.output != outputTable {
+		return writeRecords(os.Stdout, state.output, users)
 	}
 
-	feedResponse, err := rss.FetchFeed(context.Background(), feed.Url)
-	if err != nil {
-		return fmt.Errorf("Failed to fetch feed: %w", err)
-	}
-
-	fmt.Printf("Feed items from '%s':\n", feed.Url)
-	for _, feedItem := range feedResponse.Channel.Item {
-		fmt.Printf("%s\n", feedItem.Title)
-
-		publishedAt, parseErr := parseRssPublicationDate(feedItem.PubDate)
-		if parseErr != nil {
-			return fmt.Errorf("Failed to parse publication date: %w", parseErr)
+	for _, listed := range users {
+		role := ""
+		if listed.IsAdmin {
+			role = " [admin]"
 		}
-
-		post, err := state.db.CreatePost(context.Background(), database.CreatePostParams{
-			ID:        uuid.New(),
-			Url:       feedItem.Link,
-			Title:     feedItem.Title,
-			CreatedAt: time.Now(),
-			Description: sql.NullString{
-				String: feedItem.Description,
-				Valid:  true,
-			},
-			PublishedAt: sql.NullTime{
-				Time:  publishedAt,
-				Valid: true,
-			},
-			FeedID: feed.ID,
-		})
-		if err != nil {
-			var postgresErr *pg.Error
-			// If the post already exists we are just ignoring the error. 23505 is a duplicate key error
-			if errors.As(err, &postgresErr) && postgresErr.Code != "23505" {
-				return fmt.Errorf("Unexpected error occurred during post creation: %w", err)
-			}
-			continue
+		if listed.ID == user.ID {
+			fmt.Printf("* %s%s (current)\n", listed.Name, role)
+		} else {
+			fmt.Printf("* %s%s\n", listed.Name, role)
 		}
-
-		fmt.Printf("Added post '%s'\n", post.Title)
 	}
-
-	if _, err := state.db.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
-		ID:            feed.ID,
-		LastFetchedAt: sql.NullTime{Time: time.Now(), Valid: true},
-	}); err != nil {
-		return fmt.Errorf("Failed to update last fetched timestamp: %v", err)
-	}
-
 	return nil
 }
 
-func parseRssPublicationDate(rssPublicationDate string) (time.Time, error) {
-	// the input date comes in this format: Sun, 03 Dec 2023 00:00:00 +0000
-	timeLayout := "2006-Jan-02"
-	splits := strings.Split(rssPublicationDate, " ")
-	relevantSplits := splits[1:4]
-	slices.Reverse(relevantSplits)
-	joined := strings.Join(relevantSplits, "-")
-	fmt.Println(joined)
-	return time.Parse(timeLayout, joined)
-}
-
 func addFeedCommand(state *State, arguments []string, user database.User) error {
-	if len(arguments) == 0 || arguments[0] == "" {
-		return fmt.Errorf("Feed url input is missing")
+	if len(arguments) < 2 || arguments[0] == "" || arguments[1] == "" {
+		return fmt.Errorf("Feed name or url input is missing")
 	}
 	if len(arguments) > 2 {
 		return fmt.Errorf("Too many arguments! 'addfeed' command expects a 2 arguments: name and feed url")
@@ -211,9 +170,22 @@ func addFeedCommand(state *State, arguments []string, user database.User) error
 	feedUrl := arguments[1]
 	feedName := arguments[0]
 
-	// We want to make sure the the url points to a valid feed
-	if _, err := rss.FetchFeed(context.Background(), feedUrl); err != nil {
-		return fmt.Errorf("Failed to fetch feed with error: %v", err)
+	// We want to make sure the the url points to a valid feed. Website urls are
+	// resolved to the feeds they link to.
+	if _, fetchErr := rss.FetchFeed(context.Background(), feedUrl, rss.CacheValidators{}); fetchErr != nil {
+		feeds, err := discoverFeeds(feedUrl)
+		if err != nil {
+			return fmt.Errorf("Failed to fetch feed with error: %v", fetchErr)
+		}
+		selected, err := selectFeed(feeds)
+		if err != nil {
+			return err
+		}
+		// Pages may link to stale feeds or to documents that are no feeds
+		if _, err := rss.FetchFeed(context.Background(), selected.URL, rss.CacheValidators{}); err != nil {
+			return fmt.Errorf("Failed to fetch the selected feed '%s' with error: %v", selected.URL, err)
+		}
+		feedUrl = selected.URL
 	}
 
 	now := time.Now()
@@ -242,11 +214,23 @@ func addFeedCommand(state *State, arguments []string, user database.User) error
 	return nil
 }
 
-func listFeedsCommand(state *State, arguments []string) error {
+func listFeedsCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("feeds", flag.ContinueOnError)
+	health := flags.Bool("health", false, "only list failing and disabled feeds")
+	if _, err := parseFlags(flags, arguments); err != nil {
+		return err
+	}
+	if *health {
+		return listFeedHealth(state)
+	}
+
 	feeds, err := state.db.ListFeeds(context.Background())
 	if err != nil {
 		return fmt.Errorf("Failed to fetch feeds with error: %v", err)
 	}
+	if state.output != outputTable {
+		return writeRecords(os.Stdout, state.output, feeds)
+	}
 
 	fmt.Println("Name\tUrl\tUser")
 	for _, feed := range feeds {
@@ -255,6 +239,57 @@ func listFeedsCommand(state *State, arguments []string) error {
 	return nil
 }
 
+func listFeedHealth(state *State) error {
+	feeds, err := state.db.ListFeedHealth(context.Background())
+	if err != nil {
+		return fmt.Errorf("Failed to fetch feed health with error: %v", err)
+	}
+	if state.output != outputTable {
+		return writeRecords(os.Stdout, state.output, feeds)
+	}
+	if len(feeds) == 0 {
+		fmt.Println("All feeds are healthy")
+		return nil
+	}
+
+	fmt.Println("Name\tUrl\tStatus\tFailures\tLast success\tLast error")
+	for _, feed := range feeds {
+		status := "failing"
+		if feed.DisabledAt.Valid {
+			status = "disabled"
+		}
+		lastSuccess := "never"
+		if feed.LastSuccessAt.Valid {
+			lastSuccess = feed.LastSuccessAt.Time.Format(time.DateTime)
+		}
+		fmt.Printf("%s\t%s\t%s\t%d\t%s\t%s\n", feed.Name, feed.Url, status, feed.ConsecutiveFailures, lastSuccess, feed.LastError.String)
+	}
+	return nil
+}
+
+func enableFeedCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) == 0 || arguments[0] == "" {
+		return fmt.Errorf("Feed url input is missing")
+	}
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'enable-feed' command expects a single argument")
+	}
+
+	feedUrl := arguments[0]
+	if err := recordAudit(context.Background(), state, user, "enable-feed", feedUrl); err != nil {
+		return err
+	}
+	if _, err := state.db.EnableFeed(context.Background(), database.EnableFeedParams{
+		Url:       feedUrl,
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("Failed to enable feed '%s': %w", feedUrl, err)
+	}
+
+	fmt.Printf("Feed '%s' is enabled and will be fetched with the next run\n", feedUrl)
+	return nil
+}
+
 func followFeedCommand(state *State, arguments []string, user database.User) error {
 	if len(arguments) == 0 || arguments[0] == "" {
 		return fmt.Errorf("Feed url input is missing")
@@ -263,10 +298,9 @@ func followFeedCommand(state *State, arguments []string, user database.User) err
 		return fmt.Errorf("Too many arguments! 'follow' command expects a single argument")
 	}
 
-	feedUrl := arguments[0]
-	feed, err := state.db.GetFeed(context.Background(), feedUrl)
+	feed, err := findFeedForUrl(state, arguments[0])
 	if err != nil {
-		return fmt.Errorf("Failed to find feed by url: %w", err)
+		return err
 	}
 
 	now := time.Now()
@@ -287,11 +321,44 @@ func followFeedCommand(state *State, arguments []string, user database.User) err
 	return nil
 }
 
+// findFeedForUrl looks up a registered feed. If the url is not a registered
+// feed it is treated as a website and resolved to the registered feeds it links to.
+func findFeedForUrl(state *State, feedUrl string) (database.Feed, error) {
+	feed, err := state.db.GetFeed(context.Background(), feedUrl)
+	if err == nil {
+		return feed, nil
+	}
+
+	discovered, discoverErr := discoverFeeds(feedUrl)
+	if discoverErr != nil {
+		return database.Feed{}, fmt.Errorf("Failed to find feed by url: %w", err)
+	}
+
+	registered := []rss.DiscoveredFeed{}
+	for _, candidate := range discovered {
+		if _, err := state.db.GetFeed(context.Background(), candidate.URL); err == nil {
+			registered = append(registered, candidate)
+		}
+	}
+	if len(registered) == 0 {
+		return database.Feed{}, fmt.Errorf("None of the feeds of '%s' is registered yet. Add it with 'addfeed' first", feedUrl)
+	}
+
+	selected, err := selectFeed(registered)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	return state.db.GetFeed(context.Background(), selected.URL)
+}
+
 func listFollowedFeedsCommand(state *State, arguments []string, user database.User) error {
 	follows, err := state.db.GetFeedFollowsForUser(context.Background(), user.ID)
 	if err != nil {
 		return fmt.Errorf("Failed to fetch followed feeds: %w", err)
 	}
+	if state.output != outputTable {
+		return writeRecords(os.Stdout, state.output, follows)
+	}
 
 	for _, follow := range follows {
 		fmt.Printf("%s\n", follow.FeedName)
@@ -312,7 +379,7 @@ func unfollowFeedCommand(state *State, arguments []string, user database.User) e
 		UserID:  user.ID,
 		FeedUrl: feedUrl,
 	}); err != nil {
-		return fmt.Errorf("Failed to delete follow", err)
+		return fmt.Errorf("Failed to delete follow: %w", err)
 	}
 
 	fmt.Printf("Successfully unfollowed feed\n")
@@ -320,77 +387,188 @@ func unfollowFeedCommand(state *State, arguments []string, user database.User) e
 	return nil
 }
 
-func browsePostsCommand(state *State, arguments []string, user database.User) error {
+func setRefreshIntervalCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) < 2 || arguments[0] == "" || arguments[1] == "" {
+		return fmt.Errorf("'set-interval' command expects 2 arguments: feed url and an interval like '30m' or 'auto'")
+	}
+	if len(arguments) > 2 {
+		return fmt.Errorf("Too many arguments! 'set-interval' command expects 2 arguments: feed url and interval")
+	}
 
-	limit := 2
-	if len(arguments) > 0 {
-		parsedLimit, err := strconv.Atoi(arguments[0])
+	feedUrl := arguments[0]
+	interval := sql.NullInt32{}
+	if arguments[1] != "auto" {
+		duration, err := time.ParseDuration(arguments[1])
 		if err != nil {
-			return fmt.Errorf("The limit input is not a valid integer: %w", err)
+			return fmt.Errorf("interval format is invalid: %w", err)
+		}
+		if duration < schedule.MinInterval {
+			return fmt.Errorf("The interval must be at least %s", schedule.MinInterval)
 		}
-		limit = int(parsedLimit)
+		interval = sql.NullInt32{Int32: int32(duration / time.Minute), Valid: true}
 	}
 
-	posts, err := state.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
-	})
-	if err != nil {
-		return fmt.Errorf("Failed to retrieve posts: %w", err)
+	// The feed is scheduled right away so the new interval takes effect with the next fetch
+	now := time.Now()
+	if _, err := state.db.SetFeedRefreshInterval(context.Background(), database.SetFeedRefreshIntervalParams{
+		Url:                    feedUrl,
+		UserID:                 user.ID,
+		RefreshIntervalMinutes: interval,
+		NextFetchAt:            sql.NullTime{Time: now, Valid: true},
+	}); errors.Is(err, sql.ErrNoRows) {
+		if _, err := state.db.GetFeed(context.Background(), feedUrl); err != nil {
+			return fmt.Errorf("Feed with url '%s' does not exist", feedUrl)
+		}
+		return permissionError(fmt.Sprintf("Only the user who added '%s' or an admin can change its interval", feedUrl))
+	} else if err != nil {
+		return fmt.Errorf("Failed to update the interval of '%s': %w", feedUrl, err)
 	}
 
-	for _, post := range posts {
-		fmt.Printf("%s\t%s\n", post.Title, post.Url)
+	if interval.Valid {
+		fmt.Printf("Feed '%s' is now refreshed every %d minutes\n", feedUrl, interval.Int32)
+	} else {
+		fmt.Printf("Feed '%s' is now refreshed automatically\n", feedUrl)
 	}
-
 	return nil
 }
 
 func getCliCommands() map[string]cliCommand {
 	return map[string]cliCommand{
 		"login": {
-			description: "Sets the current user in the config",
+			description: "Logs in as a user with their password",
 			callback:    loginCommand,
 		},
+		"logout": {
+			description: "Ends the session of the current user",
+			callback:    logoutCommand,
+		},
+		"reset-password": {
+			description: "Removes the password, sessions, api tokens and Fever key of a user and prints a one-time token they choose a new password with at login. Admins only",
+			callback:    resetPasswordCommand,
+		},
+		"passwd": {
+			description: "Changes the password of the current user",
+			callback:    middlewareScope(scopeAccount, changePasswordCommand),
+		},
+		"create-token": {
+			description: "Creates an api token for scripts, passed in $GATOR_TOKEN. Supports --scope read|manage",
+			callback:    middlewareScope(scopeAccount, createApiTokenCommand),
+		},
+		"tokens": {
+			description: "Lists the api tokens of the current user",
+			callback:    middlewareScope(scopeAccount, listApiTokensCommand),
+		},
+		"revoke-token": {
+			description: "Revokes an api token by name",
+			callback:    middlewareScope(scopeAccount, revokeApiTokenCommand),
+		},
 		"register": {
-			description: "Registers a new user",
+			description: "Registers a new user and logs in as the user",
 			callback:    registerUserCommand,
 		},
 		"users": {
-			description: "Lists all registered users",
-			callback:    listUsersCommand,
+			description: "Lists all registered users. Admin only",
+			callback:    middlewareAdmin(scopeRead, listUsersCommand),
 		},
 		"reset": {
-			description: "Delete all users",
-			callback:    resetUsersCommand,
+			description: "Deletes all users with their feeds and posts. Admin only, asks for confirmation unless --yes is given",
+			callback:    middlewareAdmin(scopeAccount, resetUsersCommand),
+		},
+		"set-admin": {
+			description: "Grants the admin role to a user, --revoke removes it. Admin only",
+			callback:    middlewareAdmin(scopeAccount, setAdminCommand),
+		},
+		"audit": {
+			description: "Lists who ran admin commands, --limit sets the number of entries. Admin only",
+			callback:    middlewareAdmin(scopeRead, listAuditLogCommand),
 		},
 		"agg": {
-			description: "start long running aggregator service",
+			description: "start long running aggregator service. Supports --workers N and --batch M to fetch feeds concurrently. Stops gracefully on SIGINT/SIGTERM within --shutdown-timeout, SIGHUP reloads the config. --metrics-addr serves Prometheus metrics",
 			callback:    aggregateFeedsCommand,
 		},
+		"serve": {
+			description: "Serves a JSON REST api on --addr, documented at /api/openapi.json",
+			callback:    serveCommand,
+		},
+		"web": {
+			description: "Serves a web interface for reading posts on --addr",
+			callback:    webCommand,
+		},
+		"fever-password": {
+			description: "Prompts for the password Fever and Google Reader api clients log in with",
+			callback:    middlewareScope(scopeAccount, setFeverPasswordCommand),
+		},
 		"addfeed": {
-			description: "add a new RSS feed url",
+			description: "add a new RSS feed url. Website urls are resolved to the feeds they link to",
 			callback:    middlewareLoggedIn(addFeedCommand),
 		},
 		"feeds": {
-			description: "list all stored RSS feeds",
-			callback:    listFeedsCommand,
+			description: "list all stored RSS feeds, --health only lists failing feeds. Admin only",
+			callback:    middlewareAdmin(scopeRead, listFeedsCommand),
+		},
+		"enable-feed": {
+			description: "Re-enables a feed that was disabled after repeated failures. Admin only",
+			callback:    middlewareAdmin(scopeManage, enableFeedCommand),
 		},
 		"follow": {
-			description: "Follow a registered feed by url",
+			description: "Follow a registered feed by feed or website url",
 			callback:    middlewareLoggedIn(followFeedCommand),
 		},
 		"following": {
 			description: "Lists all feeds the user is following",
-			callback:    middlewareLoggedIn(listFollowedFeedsCommand),
+			callback:    middlewareScope(scopeRead, listFollowedFeedsCommand),
 		},
 		"unfollow": {
 			description: "Unfollows a given feed url",
 			callback:    middlewareLoggedIn(unfollowFeedCommand),
 		},
+		"set-interval": {
+			description: "Overrides the refresh interval of a feed for all followers, at least 5m. Restricted to the user who added the feed and admins, 'auto' restores the automatic schedule",
+			callback:    middlewareLoggedIn(setRefreshIntervalCommand),
+		},
+		"import-opml": {
+			description: "Imports and follows the feeds of an OPML file. Supports --timeout for validating new feeds",
+			callback:    middlewareLoggedIn(importOpmlCommand),
+		},
+		"export-opml": {
+			description: "Exports the followed feeds as OPML to stdout or an optional file",
+			callback:    middlewareScope(scopeRead, exportOpmlCommand),
+		},
 		"browse": {
-			description: "List saved posts with an optional limit",
-			callback:    middlewareLoggedIn(browsePostsCommand),
+			description: "Lists posts of followed feeds page by page. Supports --feed, --since, --until, --sort, --limit, --page, --after and --unread",
+			callback:    middlewareScope(scopeRead, browsePostsCommand),
+		},
+		"search": {
+			description: "Full text search over the posts of followed feeds. Supports --feed, --since, --until and --limit",
+			callback:    middlewareScope(scopeRead, searchPostsCommand),
+		},
+		"read": {
+			description: "Marks a post as read by id or url",
+			callback:    middlewareLoggedIn(markPostCommand(true)),
+		},
+		"unread": {
+			description: "Marks a post as unread by id or url",
+			callback:    middlewareLoggedIn(markPostCommand(false)),
+		},
+		"star": {
+			description: "Stars a post by id or url with an optional note",
+			callback:    middlewareLoggedIn(starPostCommand),
+		},
+		"unstar": {
+			description: "Removes the star of a post",
+			callback:    middlewareLoggedIn(unstarPostCommand),
+		},
+		"starred": {
+			description: "Lists starred posts, --feed filters by feed url",
+			callback:    middlewareScope(scopeRead, listStarredPostsCommand),
+		},
+		"export-starred": {
+			description: "Exports the starred posts as JSON to stdout or an optional file",
+			callback:    middlewareScope(scopeRead, exportStarredPostsCommand),
+		},
+		"mark-all-read": {
+			description: "Marks all posts as read. Supports --feed url and --before date filters",
+			callback:    middlewareLoggedIn(markAllReadCommand),
 		},
 	}
 }
@@ -403,11 +581,16 @@ func main() {
 		log.Fatalf("Failed to open database connection with '%s'", config.DbURL)
 	}
 
-	dbQueries := database.New(db)
+	dbQueries := newQueries(db)
+
+	// The output format is a global option of every listing command
+	output, arguments, err := extractOutputFlag(os.Args)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
 
-	state := State{config: &config, db: dbQueries}
+	state := State{config: &config, conn: db, db: dbQueries, output: output}
 
-	arguments := os.Args
 	if len(arguments) < 2 {
 		log.Fatalf("No command given. See 'help' for a list of available commands")
 	}
@@ -429,3 +612,27 @@ func loadConfig() config.Config {
 
 	return configFile
 }
+
+// reloadConfig reads the config file again. The database is reconnected if
+// its url changed.
+func reloadConfig(state *State) error {
+	reloaded, err := config.Read()
+	if err != nil {
+		return fmt.Errorf("Failed to read config: %w", err)
+	}
+	if reloaded.DbURL != state.config.DbURL {
+		db, err := sql.Open("postgres", reloaded.DbURL)
+		if err != nil {
+			return fmt.Errorf("Failed to open database connection: %w", err)
+		}
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return fmt.Errorf("Failed to connect to the database: %w", err)
+		}
+		state.conn.Close()
+		state.conn = db
+		state.db = newQueries(db)
+	}
+	*state.config = reloaded
+	return nil
+}
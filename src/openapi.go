@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var pathParameterPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// knownSchemas are the schemas of types that are not written as json objects,
+// see toJSONValue
+var knownSchemas = map[reflect.Type]map[string]any{
+	reflect.TypeOf(uuid.UUID{}):       {"type": "string", "format": "uuid"},
+	reflect.TypeOf(uuid.NullUUID{}):   {"type": "string", "format": "uuid", "nullable": true},
+	reflect.TypeOf(time.Time{}):       {"type": "string", "format": "date-time"},
+	reflect.TypeOf(sql.NullTime{}):    {"type": "string", "format": "date-time", "nullable": true},
+	reflect.TypeOf(sql.NullString{}):  {"type": "string", "nullable": true},
+	reflect.TypeOf(sql.NullBool{}):    {"type": "boolean", "nullable": true},
+	reflect.TypeOf(sql.NullInt32{}):   {"type": "integer", "nullable": true},
+	reflect.TypeOf(sql.NullInt64{}):   {"type": "integer", "nullable": true},
+	reflect.TypeOf(sql.NullFloat64{}): {"type": "number", "nullable": true},
+}
+
+// buildOpenAPISpec generates the OpenAPI 3.0 document of the api from its
+// routes. The schemas are derived from the request and response types.
+func buildOpenAPISpec(routes []apiRoute) map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]any{}
+	errorResponse := map[string]any{
+		"description": "Error",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schemaForType(reflect.TypeOf(apiErrorResponse{}), schemas)},
+		},
+	}
+
+	for _, route := range routes {
+		parameters := []any{}
+		for _, match := range pathParameterPattern.FindAllStringSubmatch(route.path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		for _, parameter := range route.parameters {
+			parameters = append(parameters, map[string]any{
+				"name":        parameter.name,
+				"in":          "query",
+				"description": parameter.description,
+				"schema":      map[string]any{"type": parameter.schemaType},
+			})
+		}
+
+		response := map[string]any{"description": http.StatusText(route.status)}
+		if route.response != nil {
+			response["content"] = map[string]any{
+				"application/json": map[string]any{"schema": schemaForType(reflect.TypeOf(route.response), schemas)},
+			}
+		}
+
+		operation := map[string]any{
+			"summary":    route.summary,
+			"parameters": parameters,
+			"responses": map[string]any{
+				strconv.Itoa(route.status): response,
+				"default":                  errorResponse,
+			},
+		}
+		if route.requestBody != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemaForType(reflect.TypeOf(route.requestBody), schemas)},
+				},
+			}
+		}
+		if !route.anonymous {
+			operation["security"] = []any{map[string]any{"user": []any{}}}
+		}
+
+		pathItem, ok := paths[route.path].(map[string]any)
+		if !ok {
+			pathItem = map[string]any{}
+			paths[route.path] = pathItem
+		}
+		pathItem[strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "gator",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"user": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "api token created with 'create-token'. It is required by every operation except creating users",
+				},
+			},
+		},
+	}
+}
+
+// schemaForType returns the schema of a type. Structs are added to the
+// components and referenced by their name.
+func schemaForType(valueType reflect.Type, schemas map[string]any) map[string]any {
+	if schema, ok := knownSchemas[valueType]; ok {
+		return schema
+	}
+
+	switch valueType.Kind() {
+	case reflect.Pointer:
+		schema := map[string]any{"nullable": true}
+		for key, value := range schemaForType(valueType.Elem(), schemas) {
+			schema[key] = value
+		}
+		// Siblings of a $ref are ignored, so references are wrapped
+		if ref, ok := schema["$ref"]; ok {
+			delete(schema, "$ref")
+			schema["allOf"] = []any{map[string]any{"$ref": ref}}
+		}
+		return schema
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaForType(valueType.Elem(), schemas)}
+	case reflect.Struct:
+		name := valueType.Name()
+		if _, ok := schemas[name]; !ok {
+			properties := map[string]any{}
+			required := []any{}
+			// Register the name first, so recursive types terminate
+			schemas[name] = map[string]any{}
+			for i := 0; i < valueType.NumField(); i++ {
+				fieldName, ok := outputFieldName(valueType.Field(i))
+				if !ok {
+					continue
+				}
+				properties[fieldName] = schemaForType(valueType.Field(i).Type, schemas)
+				required = append(required, fieldName)
+			}
+			schemas[name] = map[string]any{
+				"type":       "object",
+				"properties": properties,
+				"required":   required,
+			}
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		// Values of unknown type, e.g. interface{} columns
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/1DIce/gator/internal/opml"
+	"github.com/1DIce/gator/internal/rss"
+	"github.com/google/uuid"
+)
+
+type importReport struct {
+	imported    []string
+	duplicates  []string
+	invalid     []string
+	unreachable []string
+	failed      []string
+}
+
+func importOpmlCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("import-opml", flag.ContinueOnError)
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of a single feed request")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) == 0 || arguments[0] == "" {
+		return fmt.Errorf("OPML file input is missing")
+	}
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'import-opml' command expects a single argument")
+	}
+
+	file, err := os.Open(arguments[0])
+	if err != nil {
+		return fmt.Errorf("Failed to open OPML file: %w", err)
+	}
+	defer file.Close()
+
+	subscriptions, err := opml.Parse(file)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Importing %d feeds...\n", len(subscriptions))
+
+	report := importReport{}
+	seen := map[string]bool{}
+	for _, subscription := range subscriptions {
+		if seen[subscription.XMLURL] {
+			report.duplicates = append(report.duplicates, subscription.XMLURL)
+			continue
+		}
+		seen[subscription.XMLURL] = true
+		importSubscription(state, user, subscription, *timeout, &report)
+	}
+
+	fmt.Printf("\nImported %d feeds\n", len(report.imported))
+	printImportProblems("Already followed", report.duplicates)
+	printImportProblems("Invalid urls", report.invalid)
+	printImportProblems("Unreachable feeds", report.unreachable)
+	printImportProblems("Failed to import", report.failed)
+	return nil
+}
+
+func importSubscription(state *State, user database.User, subscription opml.Subscription, timeout time.Duration, report *importReport) {
+	feedUrl := subscription.XMLURL
+	if !isValidFeedUrl(feedUrl) {
+		report.invalid = append(report.invalid, feedUrl)
+		return
+	}
+
+	now := time.Now()
+	feed, err := state.db.GetFeed(context.Background(), feedUrl)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Feeds that are new to gator have to be reachable, like in 'addfeed'.
+		// The timeout keeps a single hanging server from stalling the import.
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		fetchResult, fetchErr := rss.FetchFeed(ctx, feedUrl, rss.CacheValidators{})
+		cancel()
+		if fetchErr != nil {
+			report.unreachable = append(report.unreachable, fmt.Sprintf("%s (%v)", feedUrl, fetchErr))
+			return
+		}
+
+		name := subscription.Title
+		if name == "" {
+			name = fetchResult.Feed.Title
+		}
+		feed, err = state.db.CreateFeed(context.Background(), database.CreateFeedParams{
+			ID:        uuid.New(),
+			Name:      name,
+			Url:       feedUrl,
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    user.ID,
+		})
+	}
+	if err != nil {
+		report.failed = append(report.failed, fmt.Sprintf("%s (%v)", feedUrl, err))
+		return
+	}
+
+	if _, err := state.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		FeedID:    feed.ID,
+		UserID:    user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}); err != nil {
+		if isDuplicateKeyError(err) {
+			report.duplicates = append(report.duplicates, feedUrl)
+		} else {
+			report.failed = append(report.failed, fmt.Sprintf("%s (%v)", feedUrl, err))
+		}
+		return
+	}
+
+	if subscription.Category != "" {
+		if err := state.db.SetFeedFollowCategory(context.Background(), database.SetFeedFollowCategoryParams{
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+			Category:  toNullString(subscription.Category),
+			UpdatedAt: now,
+		}); err != nil {
+			report.failed = append(report.failed, fmt.Sprintf("%s (failed to set category: %v)", feedUrl, err))
+			return
+		}
+	}
+
+	report.imported = append(report.imported, feedUrl)
+	fmt.Printf("Following '%s'\n", feed.Name)
+}
+
+func isValidFeedUrl(feedUrl string) bool {
+	parsed, err := url.ParseRequestURI(feedUrl)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func printImportProblems(title string, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", title, len(urls))
+	for _, feedUrl := range urls {
+		fmt.Printf("* %s\n", feedUrl)
+	}
+}
+
+func exportOpmlCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'export-opml' command expects an optional file path")
+	}
+
+	follows, err := state.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to fetch followed feeds: %w", err)
+	}
+
+	subscriptions := make([]opml.Subscription, 0, len(follows))
+	for _, follow := range follows {
+		subscriptions = append(subscriptions, opml.Subscription{
+			Title:    follow.FeedName,
+			XMLURL:   follow.FeedUrl,
+			Category: follow.Category.String,
+		})
+	}
+
+	output := os.Stdout
+	if len(arguments) == 1 && arguments[0] != "-" {
+		file, err := os.Create(arguments[0])
+		if err != nil {
+			return fmt.Errorf("Failed to create export file: %w", err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	title := fmt.Sprintf("Feeds followed by %s", user.Name)
+	if err := opml.Write(output, title, subscriptions); err != nil {
+		return fmt.Errorf("Failed to write OPML: %w", err)
+	}
+	if output != os.Stdout {
+		fmt.Printf("Exported %d feeds to '%s'\n", len(subscriptions), arguments[0])
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// outputFormat is selected with the global --output option. The table format
+// is the human readable output of each command, all other formats are written
+// by writeRecords.
+type outputFormat string
+
+const (
+	outputTable outputFormat = "table"
+	outputJSON  outputFormat = "json"
+	outputJSONL outputFormat = "jsonl"
+	outputCSV   outputFormat = "csv"
+	outputTSV   outputFormat = "tsv"
+)
+
+func parseOutputFormat(value string) (outputFormat, error) {
+	switch format := outputFormat(value); format {
+	case outputTable, outputJSON, outputJSONL, outputCSV, outputTSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("Unknown output format '%s', expected table, json, jsonl, csv or tsv", value)
+	}
+}
+
+// extractOutputFlag removes the global --output option from the command line.
+// It is accepted anywhere, e.g. "gator --output json feeds" or
+// "gator browse --unread --output json". Arguments after a "--" terminator
+// are passed on unchanged, so notes and search queries can contain "--output".
+func extractOutputFlag(arguments []string) (outputFormat, []string, error) {
+	format := outputTable
+	if len(arguments) == 0 {
+		return format, arguments, nil
+	}
+	// The first argument is the program name
+	remaining := []string{arguments[0]}
+	for i := 1; i < len(arguments); i++ {
+		argument := arguments[i]
+		if argument == "--" {
+			return format, append(remaining, arguments[i:]...), nil
+		}
+
+		value, found := "", false
+		for _, name := range []string{"-output", "--output"} {
+			if argument == name {
+				if i+1 >= len(arguments) {
+					return "", nil, fmt.Errorf("The %s option expects a format", name)
+				}
+				i++
+				value, found = arguments[i], true
+			} else if strings.HasPrefix(argument, name+"=") {
+				value, found = strings.TrimPrefix(argument, name+"="), true
+			}
+		}
+		if !found {
+			remaining = append(remaining, argument)
+			continue
+		}
+
+		parsed, err := parseOutputFormat(value)
+		if err != nil {
+			return "", nil, err
+		}
+		format = parsed
+	}
+	return format, remaining, nil
+}
+
+// outputField is a single column of a record. The name is the json tag of the
+// database model field, so every format uses the same field names.
+type outputField struct {
+	name  string
+	value any
+}
+
+type outputRecord []outputField
+
+// MarshalJSON keeps the fields in the order of the model
+func (record outputRecord) MarshalJSON() ([]byte, error) {
+	var builder strings.Builder
+	builder.WriteString("{")
+	for i, field := range record {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		name, err := json.Marshal(field.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		builder.Write(name)
+		builder.WriteString(":")
+		builder.Write(value)
+	}
+	builder.WriteString("}")
+	return []byte(builder.String()), nil
+}
+
+// writeRecords writes a slice of database rows in one of the structured
+// formats. Nullable columns are written as null in JSON and as empty values in
+// CSV and TSV.
+func writeRecords(writer io.Writer, format outputFormat, rows any) error {
+	records, err := toOutputRecords(rows)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case outputJSONL:
+		encoder := json.NewEncoder(writer)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputCSV:
+		return writeCSV(writer, rows, records)
+	case outputTSV:
+		return writeTSV(writer, rows, records)
+	default:
+		return fmt.Errorf("Output format '%s' is not supported by writeRecords", format)
+	}
+}
+
+func writeCSV(writer io.Writer, rows any, records []outputRecord) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(outputHeader(rows)); err != nil {
+		return err
+	}
+	for _, record := range records {
+		line := make([]string, 0, len(record))
+		for _, field := range record {
+			line = append(line, formatOutputValue(field.value))
+		}
+		if err := csvWriter.Write(line); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// tsvEscaper escapes the characters that would break the columns or lines of
+// a TSV file, the same way PostgreSQL's text COPY format does
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+func writeTSV(writer io.Writer, rows any, records []outputRecord) error {
+	if _, err := fmt.Fprintln(writer, strings.Join(outputHeader(rows), "\t")); err != nil {
+		return err
+	}
+	for _, record := range records {
+		line := make([]string, 0, len(record))
+		for _, field := range record {
+			line = append(line, tsvEscaper.Replace(formatOutputValue(field.value)))
+		}
+		if _, err := fmt.Fprintln(writer, strings.Join(line, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputHeader returns the field names of the row type. It is derived from the
+// type instead of the first record so empty results still have a header.
+func outputHeader(rows any) []string {
+	rowType := reflect.TypeOf(rows).Elem()
+	header := make([]string, 0, rowType.NumField())
+	for i := 0; i < rowType.NumField(); i++ {
+		if name, ok := outputFieldName(rowType.Field(i)); ok {
+			header = append(header, name)
+		}
+	}
+	return header
+}
+
+func toOutputRecords(rows any) ([]outputRecord, error) {
+	value := reflect.ValueOf(rows)
+	if value.Kind() != reflect.Slice || value.Type().Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Expected a slice of structs to write, got %T", rows)
+	}
+
+	records := make([]outputRecord, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		record, err := toOutputRecord(value.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func toOutputRecord(row reflect.Value) (outputRecord, error) {
+	record := outputRecord{}
+	for i := 0; i < row.NumField(); i++ {
+		name, ok := outputFieldName(row.Type().Field(i))
+		if !ok {
+			continue
+		}
+		fieldValue, err := toJSONValue(row.Field(i))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to convert field '%s': %w", name, err)
+		}
+		record = append(record, outputField{name: name, value: fieldValue})
+	}
+	return record, nil
+}
+
+// toJSONValue converts database rows, and values containing them, into values
+// encoding/json writes with the field names of the models. Nullable sql types
+// and uuids are unwrapped through their driver.Valuer implementation.
+func toJSONValue(value reflect.Value) (any, error) {
+	if !value.IsValid() {
+		return nil, nil
+	}
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		}
+	}
+
+	if valuer, ok := value.Interface().(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	if _, ok := value.Interface().(json.Marshaler); ok {
+		return value.Interface(), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return toJSONValue(value.Elem())
+	case reflect.Struct:
+		return toOutputRecord(value)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface(), nil
+		}
+		// Empty lists are written as [] instead of null
+		values := make([]any, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			element, err := toJSONValue(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, element)
+		}
+		return values, nil
+	default:
+		return value.Interface(), nil
+	}
+}
+
+func outputFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+func formatOutputValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case []byte:
+		return string(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
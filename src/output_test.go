@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractOutputFlag(t *testing.T) {
+	tests := []struct {
+		arguments []string
+		format    outputFormat
+		remaining []string
+	}{
+		{[]string{"gator", "feeds"}, outputTable, []string{"gator", "feeds"}},
+		{[]string{"gator", "--output", "json", "feeds"}, outputJSON, []string{"gator", "feeds"}},
+		{[]string{"gator", "-output=csv", "feeds"}, outputCSV, []string{"gator", "feeds"}},
+		{[]string{"gator", "feeds", "--output", "tsv"}, outputTSV, []string{"gator", "feeds"}},
+		{[]string{"gator", "browse", "--output=jsonl", "--limit", "5"}, outputJSONL, []string{"gator", "browse", "--limit", "5"}},
+		{[]string{"gator", "star", "12", "--output", "json"}, outputJSON, []string{"gator", "star", "12"}},
+		{[]string{"gator", "browse", "--unread", "--output", "json"}, outputJSON, []string{"gator", "browse", "--unread"}},
+		{
+			[]string{"gator", "star", "12", "--", "--output", "json"},
+			outputTable,
+			[]string{"gator", "star", "12", "--", "--output", "json"},
+		},
+		{
+			[]string{"gator", "--output", "json", "search", "--", "--output=csv"},
+			outputJSON,
+			[]string{"gator", "search", "--", "--output=csv"},
+		},
+	}
+
+	for _, test := range tests {
+		format, remaining, err := extractOutputFlag(test.arguments)
+		if err != nil {
+			t.Errorf("extractOutputFlag(%q) returned error: %v", test.arguments, err)
+			continue
+		}
+		if format != test.format || !reflect.DeepEqual(remaining, test.remaining) {
+			t.Errorf("extractOutputFlag(%q) = %s, %q, want %s, %q", test.arguments, format, remaining, test.format, test.remaining)
+		}
+	}
+}
+
+func TestExtractOutputFlagInvalid(t *testing.T) {
+	tests := [][]string{
+		{"gator", "--output"},
+		{"gator", "feeds", "--output", "xml"},
+	}
+	for _, arguments := range tests {
+		if _, _, err := extractOutputFlag(arguments); err == nil {
+			t.Errorf("extractOutputFlag(%q) returned no error", arguments)
+		}
+	}
+}
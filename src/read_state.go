@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// findPost resolves a post reference, which is either the id or the url of a post
+func findPost(state *State, reference string) (database.Post, error) {
+	if id, err := uuid.Parse(reference); err == nil {
+		return state.db.GetPost(context.Background(), id)
+	}
+	return state.db.GetPostByUrl(context.Background(), reference)
+}
+
+func markPostCommand(read bool) func(*State, []string, database.User) error {
+	commandName := "unread"
+	if read {
+		commandName = "read"
+	}
+
+	return func(state *State, arguments []string, user database.User) error {
+		if len(arguments) == 0 || arguments[0] == "" {
+			return fmt.Errorf("Post id or url input is missing")
+		}
+		if len(arguments) > 1 {
+			return fmt.Errorf("Too many arguments! '%s' command expects a single argument", commandName)
+		}
+
+		post, err := findPost(state, arguments[0])
+		if err != nil {
+			return fmt.Errorf("Failed to find post '%s': %w", arguments[0], err)
+		}
+
+		now := time.Now()
+		readAt := sql.NullTime{}
+		if read {
+			readAt = sql.NullTime{Time: now, Valid: true}
+		}
+		if _, err := state.db.SetPostRead(context.Background(), database.SetPostReadParams{
+			UserID:    user.ID,
+			PostID:    post.ID,
+			Read:      read,
+			ReadAt:    readAt,
+			CreatedAt: now,
+		}); err != nil {
+			return fmt.Errorf("Failed to update post: %w", err)
+		}
+
+		fmt.Printf("Marked '%s' as %s\n", post.Title, commandName)
+		return nil
+	}
+}
+
+func markAllReadCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("mark-all-read", flag.ContinueOnError)
+	feedUrl := flags.String("feed", "", "only mark the posts of this feed url")
+	before := flags.String("before", "", "only mark posts published before this date, e.g. 2024-01-31")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'mark-all-read' command only accepts --feed and --before")
+	}
+
+	publishedBefore, err := parseOptionalDate(*before)
+	if err != nil {
+		return fmt.Errorf("The before date is invalid: %w", err)
+	}
+
+	marked, err := state.db.MarkAllPostsRead(context.Background(), database.MarkAllPostsReadParams{
+		ReadAt:          time.Now(),
+		UserID:          user.ID,
+		FeedUrl:         toNullString(*feedUrl),
+		PublishedBefore: publishedBefore,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to mark posts as read: %w", err)
+	}
+
+	fmt.Printf("Marked %d posts as read\n", marked)
+	return nil
+}
+
+// setPostRead marks a post as read or unread for the user of a request
+func setPostRead(state *State, request *http.Request, user database.User, postID uuid.UUID, read bool) (database.PostState, error) {
+	now := time.Now()
+	readAt := sql.NullTime{}
+	if read {
+		readAt = sql.NullTime{Time: now, Valid: true}
+	}
+	postState, err := state.db.SetPostRead(request.Context(), database.SetPostReadParams{
+		UserID:    user.ID,
+		PostID:    postID,
+		Read:      read,
+		ReadAt:    readAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return database.PostState{}, fmt.Errorf("Failed to update post: %w", err)
+	}
+	return postState, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/1DIce/gator/internal/rss"
+)
+
+func searchPostsCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	feedUrl := flags.String("feed", "", "only search the posts of this feed url")
+	since := flags.String("since", "", "only include posts published at or after this date")
+	until := flags.String("until", "", "only include posts published before this date")
+	limit := flags.Int("limit", 10, "maximum number of results")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+
+	// Quoted phrases, "or" and "-term" are supported by websearch_to_tsquery
+	query := strings.TrimSpace(strings.Join(arguments, " "))
+	if query == "" {
+		return fmt.Errorf("Search query input is missing")
+	}
+	if *limit < 1 {
+		return fmt.Errorf("The limit must be a positive number")
+	}
+
+	publishedAfter, err := parseOptionalDate(*since)
+	if err != nil {
+		return fmt.Errorf("The since date is invalid: %w", err)
+	}
+	publishedBefore, err := parseOptionalDate(*until)
+	if err != nil {
+		return fmt.Errorf("The until date is invalid: %w", err)
+	}
+
+	results, err := state.db.SearchPostsForUser(context.Background(), database.SearchPostsForUserParams{
+		Query:           query,
+		UserID:          user.ID,
+		FeedUrl:         toNullString(*feedUrl),
+		PublishedAfter:  publishedAfter,
+		PublishedBefore: publishedBefore,
+		ResultLimit:     int32(*limit),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to search posts: %w", err)
+	}
+	if state.output != outputTable {
+		return writeRecords(os.Stdout, state.output, results)
+	}
+	if len(results) == 0 {
+		fmt.Println("No posts found")
+		return nil
+	}
+
+	for _, result := range results {
+		published := "unknown"
+		if result.PublishedAt.Valid {
+			published = result.PublishedAt.Time.Format(time.DateOnly)
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", result.Title, result.Url, result.FeedName, published)
+	}
+	return nil
+}
+
+func parseOptionalDate(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	date, err := rss.ParseDate(value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: date, Valid: true}, nil
+}
@@ -0,0 +1,726 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/1DIce/gator/internal/rss"
+	"github.com/google/uuid"
+)
+
+// apiTokenScheme is the scheme of the Authorization header with an api token,
+// see create-token. Every route that is not anonymous requires the header.
+const apiTokenScheme = "Bearer "
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// apiHandler handles the request of a user and returns the value written as
+// the json response body
+type apiHandler func(state *State, request *http.Request, user database.User) (any, error)
+
+type apiRoute struct {
+	method  string
+	path    string
+	summary string
+	// parameters are the documented query parameters
+	parameters []apiParameter
+	// requestBody and response are zero values of the request and response
+	// types. They are only used to generate the OpenAPI document.
+	requestBody any
+	response    any
+	status      int
+	// anonymous routes do not resolve the requesting user
+	anonymous bool
+	// admin routes affect every user and are restricted to admins
+	admin   bool
+	handler apiHandler
+}
+
+type apiParameter struct {
+	name        string
+	description string
+	// schemaType is the OpenAPI type of the parameter, e.g. "string" or "integer"
+	schemaType string
+}
+
+type apiErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type createUserRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type createFeedRequest struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+type followFeedRequest struct {
+	FeedUrl string `json:"feed_url"`
+}
+
+type starPostRequest struct {
+	Note string `json:"note"`
+}
+
+type postPage struct {
+	Posts []database.BrowsePostsForUserRow `json:"posts"`
+	// NextAfter is the cursor of the next page, null on the last page
+	NextAfter *uuid.UUID `json:"next_after"`
+}
+
+type refreshResult struct {
+	FeedUrl string `json:"feed_url"`
+	// Status is one of ok, not_modified, error or disabled
+	Status      string     `json:"status"`
+	NewPosts    int        `json:"new_posts"`
+	NextFetchAt *time.Time `json:"next_fetch_at"`
+	Error       *string    `json:"error"`
+}
+
+func serveCommand(state *State, arguments []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	address := flags.String("addr", "localhost:8080", "address the api listens on")
+	workers := flags.Int("workers", 4, "number of feeds fetched concurrently by a refresh")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of a single feed request")
+	maxFailures := flags.Int("max-failures", 10, "consecutive failures after which a feed is disabled. 0 never disables feeds")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 30*time.Second, "time the open requests get to finish after SIGINT or SIGTERM")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'serve' command only accepts flags")
+	}
+	if *workers < 1 || *maxFailures < 0 {
+		return fmt.Errorf("The number of workers must be positive and the failure threshold must not be negative")
+	}
+
+	routes := apiRoutes(aggregateOptions{
+		aggregator:  aggregatorID(),
+		workers:     *workers,
+		batch:       *workers,
+		timeout:     *timeout,
+		maxFailures: *maxFailures,
+	})
+
+	warnIfNotLoopback(*address)
+	fmt.Printf("Serving the api at http://%s/api, the OpenAPI document is at /api/openapi.json\n", *address)
+	fmt.Printf("Fever clients can connect to http://%s/fever/\n", *address)
+	fmt.Printf("Google Reader clients can connect to http://%s\n", *address)
+	// A refresh fetches its feeds concurrently, each within the feed timeout
+	writeTimeout := *timeout + time.Minute
+	return listenAndServe(*address, newAPIHandler(state, routes, *workers), writeTimeout, *shutdownTimeout)
+}
+
+// listenAndServe serves handler until SIGINT or SIGTERM. The open requests
+// then get shutdownTimeout to finish. The timeouts keep slow clients from
+// holding connections open.
+func listenAndServe(address string, handler http.Handler, writeTimeout, shutdownTimeout time.Duration) error {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	served := make(chan error, 1)
+	go func() {
+		served <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case received := <-signals:
+		fmt.Printf("Received %s, finishing the open requests within %s\n", received, shutdownTimeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+		return fmt.Errorf("Failed to finish the open requests: %w", err)
+	}
+	fmt.Println("Server stopped")
+	return nil
+}
+
+// warnIfNotLoopback warns when the server is reachable from other hosts. The
+// server speaks plain http, so tokens and passwords should only travel
+// through a TLS proxy in front of it.
+func warnIfNotLoopback(address string) {
+	host, _, err := net.SplitHostPort(address)
+	if err == nil && host == "localhost" {
+		return
+	}
+	if ip := net.ParseIP(host); err == nil && ip != nil && ip.IsLoopback() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s is reachable from other hosts over plain http, put a TLS proxy in front of it\n", address)
+}
+
+// newAPIHandler serves the routes and the Fever and Google Reader apis.
+// Favicons are fetched by at most workers requests at a time.
+func newAPIHandler(state *State, routes []apiRoute, workers int) http.Handler {
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		mux.HandleFunc(route.method+" "+route.path, route.serve(state))
+	}
+
+	// Fever clients are configured with the url of the endpoint, with or
+	// without trailing slash
+	fever := newFeverServer(state, workers)
+	mux.Handle("/fever", fever)
+	mux.Handle("/fever/", fever)
+	reader := newReaderServer(state)
+	reader.register(mux)
+
+	spec := buildOpenAPISpec(routes)
+	mux.HandleFunc("GET /api/openapi.json", func(writer http.ResponseWriter, request *http.Request) {
+		writeJSONResponse(writer, http.StatusOK, spec)
+	})
+	return reader.rawStreamPaths(mux)
+}
+
+func apiRoutes(options aggregateOptions) []apiRoute {
+	postParameters := []apiParameter{
+		{name: "feed", description: "only list the posts of this feed url", schemaType: "string"},
+		{name: "since", description: "only include posts published at or after this date", schemaType: "string"},
+		{name: "until", description: "only include posts published before this date", schemaType: "string"},
+		{name: "sort", description: "'newest' (default) or 'oldest'", schemaType: "string"},
+		{name: "unread", description: "only list posts that are not marked as read", schemaType: "boolean"},
+		{name: "limit", description: fmt.Sprintf("posts per page, at most %d", maxPageSize), schemaType: "integer"},
+		{name: "page", description: "page number, starting at 1", schemaType: "integer"},
+		{name: "after", description: "cursor of the next page as returned in next_after", schemaType: "string"},
+	}
+
+	return []apiRoute{
+		{
+			method: "GET", path: "/api/users", summary: "Lists all users. Admin only",
+			response: []database.User{}, status: http.StatusOK, admin: true,
+			handler: listUsersHandler,
+		},
+		{
+			method: "POST", path: "/api/users", summary: "Registers a user",
+			requestBody: createUserRequest{}, response: database.User{}, status: http.StatusCreated, anonymous: true,
+			handler: createUserHandler,
+		},
+		{
+			method: "GET", path: "/api/feeds", summary: "Lists all feeds. Admin only",
+			response: []database.ListFeedsRow{}, status: http.StatusOK, admin: true,
+			handler: listFeedsHandler,
+		},
+		{
+			method: "POST", path: "/api/feeds", summary: "Adds a feed and follows it",
+			requestBody: createFeedRequest{}, response: database.Feed{}, status: http.StatusCreated,
+			handler: createFeedHandler,
+		},
+		{
+			method: "POST", path: "/api/feeds/refresh", summary: "Fetches the feeds that are due, or a single feed",
+			parameters: []apiParameter{
+				{name: "feed", description: "url of a feed to fetch regardless of its schedule", schemaType: "string"},
+			},
+			response: []refreshResult{}, status: http.StatusOK,
+			handler: refreshFeedsHandler(options),
+		},
+		{
+			method: "GET", path: "/api/follows", summary: "Lists the feeds the user follows",
+			response: []database.GetFeedFollowsForUserRow{}, status: http.StatusOK,
+			handler: listFollowsHandler,
+		},
+		{
+			method: "POST", path: "/api/follows", summary: "Follows a registered feed",
+			requestBody: followFeedRequest{}, response: database.CreateFeedFollowRow{}, status: http.StatusCreated,
+			handler: followFeedHandler,
+		},
+		{
+			method: "DELETE", path: "/api/follows", summary: "Unfollows a feed",
+			parameters: []apiParameter{
+				{name: "feed_url", description: "url of the feed to unfollow", schemaType: "string"},
+			},
+			status:  http.StatusNoContent,
+			handler: unfollowFeedHandler,
+		},
+		{
+			method: "GET", path: "/api/posts", summary: "Lists the posts of the followed feeds page by page",
+			parameters: postParameters, response: postPage{}, status: http.StatusOK,
+			handler: listPostsHandler,
+		},
+		{
+			method: "PUT", path: "/api/posts/{id}/read", summary: "Marks a post as read",
+			response: database.PostState{}, status: http.StatusOK,
+			handler: markPostHandler(true),
+		},
+		{
+			method: "DELETE", path: "/api/posts/{id}/read", summary: "Marks a post as unread",
+			response: database.PostState{}, status: http.StatusOK,
+			handler: markPostHandler(false),
+		},
+		{
+			method: "GET", path: "/api/starred", summary: "Lists the starred posts",
+			parameters: []apiParameter{
+				{name: "feed", description: "only list the starred posts of this feed url", schemaType: "string"},
+			},
+			response: []database.GetStarredPostsForUserRow{}, status: http.StatusOK,
+			handler: listStarredHandler,
+		},
+		{
+			method: "PUT", path: "/api/posts/{id}/star", summary: "Stars a post with an optional note",
+			requestBody: starPostRequest{}, response: database.StarredPost{}, status: http.StatusOK,
+			handler: starPostHandler,
+		},
+		{
+			method: "DELETE", path: "/api/posts/{id}/star", summary: "Removes the star of a post",
+			status:  http.StatusNoContent,
+			handler: unstarPostHandler,
+		},
+	}
+}
+
+func (route apiRoute) serve(state *State) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		user := database.User{}
+		if !route.anonymous {
+			var scope accessScope
+			var err error
+			user, scope, err = requestUser(state, request)
+			if err != nil {
+				writer.Header().Set("WWW-Authenticate", "Bearer")
+				writeAPIError(writer, http.StatusUnauthorized, err)
+				return
+			}
+			if required := route.scope(); !scope.allows(required) {
+				writeAPIError(writer, http.StatusForbidden, fmt.Errorf("The request requires the '%s' scope", required))
+				return
+			}
+			if route.admin && !user.IsAdmin {
+				writeAPIError(writer, http.StatusForbidden, fmt.Errorf("User '%s' is not an admin", user.Name))
+				return
+			}
+		}
+
+		response, err := route.handler(state, request, user)
+		if err != nil {
+			status := apiErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Printf("%s %s failed: %v", request.Method, request.URL.Path, err)
+			}
+			writeAPIError(writer, status, err)
+			return
+		}
+		if route.status == http.StatusNoContent {
+			writer.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSONResponse(writer, route.status, response)
+	}
+}
+
+// scope returns the scope a route requires. Reading requires the read scope,
+// any change the manage scope.
+func (route apiRoute) scope() accessScope {
+	if route.method == http.MethodGet {
+		return scopeRead
+	}
+	return scopeManage
+}
+
+// requestUser returns the user of the api token of a request, see
+// apiTokenScheme. The session of the server's own config is never used, it
+// belongs to whoever started the server.
+func requestUser(state *State, request *http.Request) (database.User, accessScope, error) {
+	header := request.Header.Get("Authorization")
+	if header == "" {
+		return database.User{}, "", fmt.Errorf("The request requires an api token in the Authorization header, create one with 'create-token'")
+	}
+	token, ok := strings.CutPrefix(header, apiTokenScheme)
+	if !ok {
+		return database.User{}, "", fmt.Errorf("Unsupported Authorization header, expected an api token")
+	}
+	return apiTokenUser(request.Context(), state, token)
+}
+
+func apiErrorStatus(err error) int {
+	var invalid validationError
+	var forbidden permissionError
+	switch {
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest
+	case errors.As(err, &forbidden):
+		return http.StatusForbidden
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case isDuplicateKeyError(err):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeAPIError(writer http.ResponseWriter, status int, err error) {
+	writeJSONResponse(writer, status, apiErrorResponse{Error: err.Error()})
+}
+
+// writeJSONResponse writes database rows with the field names of the models,
+// see toJSONValue
+func writeJSONResponse(writer http.ResponseWriter, status int, response any) {
+	body, err := toJSONValue(reflect.ValueOf(response))
+	if err != nil {
+		log.Printf("Failed to convert response: %v", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(body); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func decodeRequestBody(request *http.Request, body any) error {
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(body); err != nil {
+		return validationError(fmt.Sprintf("The request body is invalid: %v", err))
+	}
+	return nil
+}
+
+// findPathPost returns the post of the {id} path parameter
+func findPathPost(state *State, request *http.Request) (database.Post, error) {
+	id, err := uuid.Parse(request.PathValue("id"))
+	if err != nil {
+		return database.Post{}, validationError(fmt.Sprintf("The post id is invalid: %v", err))
+	}
+	post, err := state.db.GetPost(request.Context(), id)
+	if err != nil {
+		return database.Post{}, fmt.Errorf("Failed to find post '%s': %w", id, err)
+	}
+	return post, nil
+}
+
+// findUserPathPost returns the post of the id path parameter if it belongs to
+// a feed the user follows
+func findUserPathPost(state *State, request *http.Request, user database.User) (database.Post, error) {
+	id, err := uuid.Parse(request.PathValue("id"))
+	if err != nil {
+		return database.Post{}, validationError(fmt.Sprintf("The post id is invalid: %v", err))
+	}
+	post, err := state.db.GetPostForUser(request.Context(), database.GetPostForUserParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return database.Post{}, fmt.Errorf("Failed to find post '%s' in your feeds: %w", id, err)
+	}
+	return post, nil
+}
+
+func parseIntParameter(request *http.Request, name string, defaultValue int) (int, error) {
+	value := request.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, validationError(fmt.Sprintf("The %s parameter is not a valid integer", name))
+	}
+	return parsed, nil
+}
+
+func listUsersHandler(state *State, request *http.Request, user database.User) (any, error) {
+	return state.db.GetUsers(request.Context())
+}
+
+func createUserHandler(state *State, request *http.Request, user database.User) (any, error) {
+	var body createUserRequest
+	if err := decodeRequestBody(request, &body); err != nil {
+		return nil, err
+	}
+	if body.Name == "" {
+		return nil, validationError("User name is missing")
+	}
+	passwordHash, err := hashPassword(body.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Registration is anonymous, so the api never creates the first admin.
+	// The instance is bootstrapped with 'register' at the CLI.
+	now := time.Now()
+	created, err := state.db.CreateUser(request.Context(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      body.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := state.db.SetUserPassword(request.Context(), database.SetUserPasswordParams{
+		UserID:       created.ID,
+		PasswordHash: passwordHash,
+		CreatedAt:    now,
+	}); err != nil {
+		return nil, fmt.Errorf("Failed to set password: %w", err)
+	}
+	return created, nil
+}
+
+func listFeedsHandler(state *State, request *http.Request, user database.User) (any, error) {
+	return state.db.ListFeeds(request.Context())
+}
+
+func createFeedHandler(state *State, request *http.Request, user database.User) (any, error) {
+	var body createFeedRequest
+	if err := decodeRequestBody(request, &body); err != nil {
+		return nil, err
+	}
+	if body.Name == "" || body.Url == "" {
+		return nil, validationError("Feed name or url is missing")
+	}
+	if _, err := rss.FetchFeed(request.Context(), body.Url, rss.CacheValidators{}); err != nil {
+		return nil, validationError(fmt.Sprintf("Failed to fetch feed with error: %v", err))
+	}
+
+	now := time.Now()
+	feed, err := state.db.CreateFeed(request.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		Name:      body.Name,
+		Url:       body.Url,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to add feed for url '%s': %w", body.Url, err)
+	}
+	if _, err := state.db.CreateFeedFollow(request.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		FeedID:    feed.ID,
+		UserID:    user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}); err != nil {
+		return nil, fmt.Errorf("Failed to follow feed: %w", err)
+	}
+	return feed, nil
+}
+
+func refreshFeedsHandler(options aggregateOptions) apiHandler {
+	return func(state *State, request *http.Request, user database.User) (any, error) {
+		feeds := []database.Feed{}
+		if feedUrl := request.URL.Query().Get("feed"); feedUrl != "" {
+			feed, err := state.db.GetFeed(request.Context(), feedUrl)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to find feed '%s': %w", feedUrl, err)
+			}
+			feeds = append(feeds, feed)
+		} else {
+			claimed, err := claimFeeds(request.Context(), state, options, options.batch)
+			if err != nil {
+				return nil, err
+			}
+			feeds = claimed
+		}
+
+		results := []refreshResult{}
+		for result := range scrapeFeedsConcurrently(request.Context(), state, feeds, options) {
+			results = append(results, toRefreshResult(result))
+		}
+		return results, nil
+	}
+}
+
+func toRefreshResult(result scrapeResult) refreshResult {
+	refresh := refreshResult{
+		FeedUrl:  result.feed.Url,
+		Status:   "ok",
+		NewPosts: result.newPosts,
+	}
+	if !result.nextFetchAt.IsZero() {
+		refresh.NextFetchAt = &result.nextFetchAt
+	}
+	if result.err != nil {
+		message := result.err.Error()
+		refresh.Error = &message
+	}
+
+	switch {
+	case result.disabled:
+		refresh.Status = "disabled"
+	case result.err != nil:
+		refresh.Status = "error"
+	case result.notModified:
+		refresh.Status = "not_modified"
+	}
+	return refresh
+}
+
+func listFollowsHandler(state *State, request *http.Request, user database.User) (any, error) {
+	return state.db.GetFeedFollowsForUser(request.Context(), user.ID)
+}
+
+func followFeedHandler(state *State, request *http.Request, user database.User) (any, error) {
+	var body followFeedRequest
+	if err := decodeRequestBody(request, &body); err != nil {
+		return nil, err
+	}
+	if body.FeedUrl == "" {
+		return nil, validationError("Feed url is missing")
+	}
+
+	feed, err := state.db.GetFeed(request.Context(), body.FeedUrl)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find feed by url: %w", err)
+	}
+	now := time.Now()
+	return state.db.CreateFeedFollow(request.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		FeedID:    feed.ID,
+		UserID:    user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+func unfollowFeedHandler(state *State, request *http.Request, user database.User) (any, error) {
+	feedUrl := request.URL.Query().Get("feed_url")
+	if feedUrl == "" {
+		return nil, validationError("The feed_url parameter is missing")
+	}
+	if _, err := state.db.DeleteFeedFollow(request.Context(), database.DeleteFeedFollowParams{
+		UserID:  user.ID,
+		FeedUrl: feedUrl,
+	}); err != nil {
+		return nil, fmt.Errorf("Failed to delete follow: %w", err)
+	}
+	return nil, nil
+}
+
+func listPostsHandler(state *State, request *http.Request, user database.User) (any, error) {
+	query := request.URL.Query()
+	limit, err := parseIntParameter(request, "limit", defaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if limit > maxPageSize {
+		return nil, validationError(fmt.Sprintf("The limit must not be larger than %d", maxPageSize))
+	}
+	page, err := parseIntParameter(request, "page", 1)
+	if err != nil {
+		return nil, err
+	}
+	unreadOnly := false
+	if value := query.Get("unread"); value != "" {
+		if unreadOnly, err = strconv.ParseBool(value); err != nil {
+			return nil, validationError("The unread parameter must be true or false")
+		}
+	}
+	sortOrder := query.Get("sort")
+	if sortOrder == "" {
+		sortOrder = "newest"
+	}
+
+	posts, err := browsePosts(state, user, browseOptions{
+		feedUrl:    query.Get("feed"),
+		since:      query.Get("since"),
+		until:      query.Get("until"),
+		sortOrder:  sortOrder,
+		unreadOnly: unreadOnly,
+		limit:      limit,
+		page:       page,
+		after:      query.Get("after"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := postPage{Posts: posts}
+	if len(posts) == limit {
+		result.NextAfter = &posts[len(posts)-1].ID
+	}
+	return result, nil
+}
+
+func markPostHandler(read bool) apiHandler {
+	return func(state *State, request *http.Request, user database.User) (any, error) {
+		post, err := findUserPathPost(state, request, user)
+		if err != nil {
+			return nil, err
+		}
+		return setPostRead(state, request, user, post.ID, read)
+	}
+}
+
+func listStarredHandler(state *State, request *http.Request, user database.User) (any, error) {
+	return state.db.GetStarredPostsForUser(request.Context(), database.GetStarredPostsForUserParams{
+		UserID:  user.ID,
+		FeedUrl: toNullString(request.URL.Query().Get("feed")),
+	})
+}
+
+func starPostHandler(state *State, request *http.Request, user database.User) (any, error) {
+	post, err := findUserPathPost(state, request, user)
+	if err != nil {
+		return nil, err
+	}
+	// The note is optional, so is the body
+	var body starPostRequest
+	if request.ContentLength != 0 {
+		if err := decodeRequestBody(request, &body); err != nil {
+			return nil, err
+		}
+	}
+
+	return state.db.StarPost(request.Context(), database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		Note:      toNullString(body.Note),
+		CreatedAt: time.Now(),
+	})
+}
+
+func unstarPostHandler(state *State, request *http.Request, user database.User) (any, error) {
+	post, err := findPathPost(state, request)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := state.db.UnstarPost(request.Context(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unstar post: %w", err)
+	}
+	if removed == 0 {
+		return nil, fmt.Errorf("Post '%s' is not starred: %w", post.ID, sql.ErrNoRows)
+	}
+	return nil, nil
+}
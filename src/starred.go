@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+)
+
+// starredPostExport is the format of a post written by 'export-starred'
+type starredPostExport struct {
+	Title       string     `json:"title"`
+	Url         string     `json:"url"`
+	FeedName    string     `json:"feed_name"`
+	FeedUrl     string     `json:"feed_url"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	StarredAt   time.Time  `json:"starred_at"`
+	Note        string     `json:"note,omitempty"`
+}
+
+func starPostCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) == 0 || arguments[0] == "" {
+		return fmt.Errorf("Post id or url input is missing")
+	}
+
+	post, err := findPost(state, arguments[0])
+	if err != nil {
+		return fmt.Errorf("Failed to find post '%s': %w", arguments[0], err)
+	}
+
+	// Everything after the post reference is the optional note. Notes that
+	// contain "--output" follow a "--" terminator.
+	noteArguments := arguments[1:]
+	if len(noteArguments) > 0 && noteArguments[0] == "--" {
+		noteArguments = noteArguments[1:]
+	}
+	note := strings.Join(noteArguments, " ")
+	if _, err := state.db.StarPost(context.Background(), database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		Note:      toNullString(note),
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("Failed to star post: %w", err)
+	}
+
+	fmt.Printf("Starred '%s'\n", post.Title)
+	return nil
+}
+
+func unstarPostCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) == 0 || arguments[0] == "" {
+		return fmt.Errorf("Post id or url input is missing")
+	}
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'unstar' command expects a single argument")
+	}
+
+	post, err := findPost(state, arguments[0])
+	if err != nil {
+		return fmt.Errorf("Failed to find post '%s': %w", arguments[0], err)
+	}
+
+	removed, err := state.db.UnstarPost(context.Background(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to unstar post: %w", err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("The post '%s' is not starred", post.Title)
+	}
+
+	fmt.Printf("Unstarred '%s'\n", post.Title)
+	return nil
+}
+
+func listStarredPostsCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("starred", flag.ContinueOnError)
+	feedUrl := flags.String("feed", "", "only list the starred posts of this feed url")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'starred' command only accepts --feed")
+	}
+
+	posts, err := state.db.GetStarredPostsForUser(context.Background(), database.GetStarredPostsForUserParams{
+		UserID:  user.ID,
+		FeedUrl: toNullString(*feedUrl),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve starred posts: %w", err)
+	}
+	if state.output != outputTable {
+		return writeRecords(os.Stdout, state.output, posts)
+	}
+
+	for _, post := range posts {
+		if post.Note.Valid {
+			fmt.Printf("%s\t%s\t%s\n", post.Title, post.Url, post.Note.String)
+		} else {
+			fmt.Printf("%s\t%s\n", post.Title, post.Url)
+		}
+	}
+	return nil
+}
+
+func exportStarredPostsCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'export-starred' command expects an optional file path")
+	}
+
+	posts, err := state.db.GetStarredPostsForUser(context.Background(), database.GetStarredPostsForUserParams{
+		UserID: user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve starred posts: %w", err)
+	}
+
+	exports := make([]starredPostExport, 0, len(posts))
+	for _, post := range posts {
+		export := starredPostExport{
+			Title:     post.Title,
+			Url:       post.Url,
+			FeedName:  post.FeedName,
+			FeedUrl:   post.FeedUrl,
+			StarredAt: post.StarredAt,
+			Note:      post.Note.String,
+		}
+		if post.PublishedAt.Valid {
+			export.PublishedAt = &post.PublishedAt.Time
+		}
+		exports = append(exports, export)
+	}
+
+	output := os.Stdout
+	if len(arguments) == 1 && arguments[0] != "-" {
+		file, err := os.Create(arguments[0])
+		if err != nil {
+			return fmt.Errorf("Failed to create export file: %w", err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(exports); err != nil {
+		return fmt.Errorf("Failed to write starred posts: %w", err)
+	}
+	if output != os.Stdout {
+		fmt.Printf("Exported %d starred posts to '%s'\n", len(exports), arguments[0])
+	}
+	return nil
+}
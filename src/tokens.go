@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// apiTokenPrefix marks api tokens, so they are recognizable in scripts and
+// can be told apart from other credentials
+const apiTokenPrefix = "gtr_"
+
+// isApiToken tells api tokens apart from the "name/key" tokens of the Google
+// Reader api
+func isApiToken(token string) bool {
+	return strings.HasPrefix(token, apiTokenPrefix) && !strings.Contains(token, "/")
+}
+
+func createApiTokenCommand(state *State, arguments []string, user database.User) error {
+	flags := flag.NewFlagSet("create-token", flag.ContinueOnError)
+	scope := flags.String("scope", string(scopeRead), "scope of the token: read or manage")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) == 0 || arguments[0] == "" {
+		return fmt.Errorf("Token name input is missing")
+	}
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'create-token' command expects a single argument")
+	}
+	if accessScope(*scope) != scopeRead && accessScope(*scope) != scopeManage {
+		return fmt.Errorf("Invalid scope '%s'. Valid scopes are read and manage", *scope)
+	}
+
+	random, err := newToken()
+	if err != nil {
+		return err
+	}
+	token := apiTokenPrefix + random
+	created, err := state.db.CreateApiToken(context.Background(), database.CreateApiTokenParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      arguments[0],
+		TokenHash: hashToken(token),
+		Scope:     *scope,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return fmt.Errorf("A token named '%s' already exists", arguments[0])
+		}
+		return fmt.Errorf("Failed to create token: %w", err)
+	}
+
+	fmt.Printf("Created token '%s' with scope '%s'. It is only shown once:\n", created.Name, created.Scope)
+	fmt.Println(token)
+	return nil
+}
+
+func listApiTokensCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'tokens' command expects no arguments")
+	}
+	tokens, err := state.db.GetApiTokensForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to fetch tokens: %w", err)
+	}
+	if state.output != outputTable {
+		return writeRecords(os.Stdout, state.output, tokens)
+	}
+
+	for _, token := range tokens {
+		lastUsed := "never used"
+		if token.LastUsedAt.Valid {
+			lastUsed = "last used " + token.LastUsedAt.Time.Format(time.DateTime)
+		}
+		fmt.Printf("* %s (%s), created %s, %s\n", token.Name, token.Scope, token.CreatedAt.Format(time.DateTime), lastUsed)
+	}
+	return nil
+}
+
+func revokeApiTokenCommand(state *State, arguments []string, user database.User) error {
+	if len(arguments) == 0 || arguments[0] == "" {
+		return fmt.Errorf("Token name input is missing")
+	}
+	if len(arguments) > 1 {
+		return fmt.Errorf("Too many arguments! 'revoke-token' command expects a single argument")
+	}
+
+	deleted, err := state.db.DeleteApiToken(context.Background(), database.DeleteApiTokenParams{
+		UserID: user.ID,
+		Name:   arguments[0],
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to revoke token: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("No token named '%s' exists", arguments[0])
+	}
+	fmt.Printf("Revoked token '%s'\n", arguments[0])
+	return nil
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/1DIce/gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+//go:embed web/templates web/static
+var webFiles embed.FS
+
+const webPageSize = 30
+
+// webSessionCookie holds the session token of the web interface. Sessions are
+// created by the login form and stored like the sessions of 'login'.
+const webSessionCookie = "gator_session"
+
+// errWebLoggedOut is returned for requests without a valid session cookie
+var errWebLoggedOut = errors.New("You are not logged in")
+
+// webPage is the data of every page. The sidebar is part of the layout, so
+// every page contains the followed feeds.
+type webPage struct {
+	User database.User
+	// CSRFToken is submitted with every form, see csrfToken
+	CSRFToken    string
+	Feeds        []database.GetUnreadCountsForUserRow
+	TotalUnread  int64
+	SelectedFeed string
+	UnreadOnly   bool
+	Posts        []database.BrowsePostsForUserRow
+	NextAfter    string
+	Post         database.Post
+	// Content is the sanitized description of Post
+	Content template.HTML
+	Error   string
+}
+
+// webHandler renders a page for the user or returns an error, which is shown
+// on an error page
+type webHandler func(state *State, writer http.ResponseWriter, request *http.Request, user database.User) error
+
+type webServer struct {
+	state     *State
+	templates map[string]*template.Template
+	// sanitizer removes scripts, styles and event handlers from post content.
+	// Feeds are fetched from third parties, so their html is never trusted.
+	sanitizer *bluemonday.Policy
+}
+
+func webCommand(state *State, arguments []string) error {
+	flags := flag.NewFlagSet("web", flag.ContinueOnError)
+	address := flags.String("addr", "localhost:8081", "address the web interface listens on")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 30*time.Second, "time the open requests get to finish after SIGINT or SIGTERM")
+	arguments, err := parseFlags(flags, arguments)
+	if err != nil {
+		return err
+	}
+	if len(arguments) > 0 {
+		return fmt.Errorf("Too many arguments! 'web' command only accepts flags")
+	}
+
+	mux, err := newWebServeMux(state)
+	if err != nil {
+		return err
+	}
+	warnIfNotLoopback(*address)
+	fmt.Printf("Serving the web interface at http://%s\n", *address)
+	return listenAndServe(*address, mux, 30*time.Second, *shutdownTimeout)
+}
+
+func newWebServeMux(state *State) (*http.ServeMux, error) {
+	templates := map[string]*template.Template{}
+	functions := template.FuncMap{"formatDate": formatPublicationDate}
+	for _, page := range []string{"login", "posts", "post", "error"} {
+		parsed, err := template.New(page).Funcs(functions).ParseFS(webFiles, "web/templates/layout.html", "web/templates/"+page+".html")
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse template '%s': %w", page, err)
+		}
+		templates[page] = parsed
+	}
+	static, err := fs.Sub(webFiles, "web/static")
+	if err != nil {
+		return nil, err
+	}
+
+	server := webServer{
+		state:     state,
+		templates: templates,
+		sanitizer: bluemonday.UGCPolicy().RequireNoReferrerOnLinks(true).AddTargetBlankToFullyQualifiedLinks(true),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /login", server.loginPage)
+	mux.HandleFunc("POST /login", server.login)
+	mux.HandleFunc("POST /logout", server.handle(server.logout))
+	mux.HandleFunc("GET /{$}", server.handle(server.postsPage))
+	mux.HandleFunc("GET /posts/{id}", server.handle(server.postPage))
+	mux.HandleFunc("POST /posts/{id}/read", server.handle(server.markRead))
+	mux.HandleFunc("POST /posts/{id}/unread", server.handle(server.markUnread))
+	mux.HandleFunc("POST /follow", server.handle(server.follow))
+	mux.HandleFunc("POST /unfollow", server.handle(server.unfollow))
+	return mux, nil
+}
+
+func (server webServer) handle(handler webHandler) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		// Forms may only be submitted from the pages of this server
+		if request.Method == http.MethodPost && !isSameOrigin(request) {
+			http.Error(writer, "Cross origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+
+		user, token, err := server.sessionUser(request)
+		if errors.Is(err, errWebLoggedOut) && request.Method == http.MethodGet {
+			http.Redirect(writer, request, "/login", http.StatusSeeOther)
+			return
+		}
+		if errors.Is(err, errWebLoggedOut) {
+			server.renderError(writer, http.StatusUnauthorized, webPage{}, err)
+			return
+		}
+		if err != nil {
+			log.Printf("%s %s failed: %v", request.Method, request.URL.Path, err)
+			server.renderError(writer, http.StatusInternalServerError, webPage{}, err)
+			return
+		}
+		if request.Method == http.MethodPost && !validCSRFToken(request, token) {
+			server.renderError(writer, http.StatusForbidden, webPage{}, permissionError("The form is outdated, reload the page and try again"))
+			return
+		}
+		if err := handler(server.state, writer, request, user); err != nil {
+			status := apiErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.Printf("%s %s failed: %v", request.Method, request.URL.Path, err)
+			}
+			page, sidebarErr := server.newPage(request, user)
+			if sidebarErr != nil {
+				page = webPage{User: user, CSRFToken: csrfToken(token)}
+			}
+			server.renderError(writer, status, page, err)
+		}
+	}
+}
+
+// isSameOrigin rejects form submissions from other sites. Browsers send the
+// Origin header with POST requests, or at least Sec-Fetch-Site. Requests with
+// neither are rejected.
+func isSameOrigin(request *http.Request) bool {
+	origin := request.Header.Get("Origin")
+	if origin == "" {
+		return request.Header.Get("Sec-Fetch-Site") == "same-origin"
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && parsed.Host == request.Host
+}
+
+// sessionUser returns the user and token of the session cookie
+func (server webServer) sessionUser(request *http.Request) (database.User, string, error) {
+	cookie, err := request.Cookie(webSessionCookie)
+	if err != nil || cookie.Value == "" {
+		return database.User{}, "", errWebLoggedOut
+	}
+	user, err := server.state.db.GetUserBySession(request.Context(), database.GetUserBySessionParams{
+		TokenHash: hashToken(cookie.Value),
+		Now:       time.Now(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, "", errWebLoggedOut
+	}
+	if err != nil {
+		return database.User{}, "", fmt.Errorf("Failed to validate session: %w", err)
+	}
+	return user, cookie.Value, nil
+}
+
+// csrfToken returns the token forms of a session submit. It is derived from
+// the session token, which other sites cannot read.
+func csrfToken(sessionToken string) string {
+	return hashToken("csrf " + sessionToken)
+}
+
+func validCSRFToken(request *http.Request, sessionToken string) bool {
+	submitted := request.PostFormValue("csrf_token")
+	return subtle.ConstantTimeCompare([]byte(submitted), []byte(csrfToken(sessionToken))) == 1
+}
+
+func (server webServer) loginPage(writer http.ResponseWriter, request *http.Request) {
+	server.render(writer, http.StatusOK, "login", webPage{})
+}
+
+// login checks the password of the form and starts a session. Users without
+// a password choose one with 'login' first.
+func (server webServer) login(writer http.ResponseWriter, request *http.Request) {
+	if !isSameOrigin(request) {
+		http.Error(writer, "Cross origin requests are not allowed", http.StatusForbidden)
+		return
+	}
+
+	user, err := server.state.db.GetUser(request.Context(), request.PostFormValue("name"))
+	if err == nil {
+		err = checkPassword(request.Context(), server.state, user, request.PostFormValue("password"))
+	}
+	if err != nil {
+		server.render(writer, http.StatusUnauthorized, "login", webPage{Error: "Wrong user name or password"})
+		return
+	}
+
+	token, expiresAt, err := createSession(request.Context(), server.state, user)
+	if err != nil {
+		log.Printf("%s %s failed: %v", request.Method, request.URL.Path, err)
+		server.render(writer, http.StatusInternalServerError, "login", webPage{Error: err.Error()})
+		return
+	}
+	http.SetCookie(writer, &http.Cookie{
+		Name:     webSessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(writer, request, "/", http.StatusSeeOther)
+}
+
+func (server webServer) logout(state *State, writer http.ResponseWriter, request *http.Request, user database.User) error {
+	cookie, err := request.Cookie(webSessionCookie)
+	if err != nil {
+		return errWebLoggedOut
+	}
+	if err := state.db.DeleteSession(request.Context(), hashToken(cookie.Value)); err != nil {
+		return fmt.Errorf("Failed to delete session: %w", err)
+	}
+	http.SetCookie(writer, &http.Cookie{
+		Name:     webSessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(writer, request, "/login", http.StatusSeeOther)
+	return nil
+}
+
+// newPage loads the sidebar of the user
+func (server webServer) newPage(request *http.Request, user database.User) (webPage, error) {
+	feeds, err := server.state.db.GetUnreadCountsForUser(request.Context(), user.ID)
+	if err != nil {
+		return webPage{}, fmt.Errorf("Failed to count unread posts: %w", err)
+	}
+
+	cookie, err := request.Cookie(webSessionCookie)
+	if err != nil {
+		return webPage{}, errWebLoggedOut
+	}
+	page := webPage{
+		User:         user,
+		CSRFToken:    csrfToken(cookie.Value),
+		Feeds:        feeds,
+		SelectedFeed: request.URL.Query().Get("feed"),
+	}
+	for _, feed := range feeds {
+		page.TotalUnread += feed.UnreadCount
+	}
+	if value := request.URL.Query().Get("unread"); value != "" {
+		page.UnreadOnly, _ = strconv.ParseBool(value)
+	}
+	return page, nil
+}
+
+func (server webServer) postsPage(state *State, writer http.ResponseWriter, request *http.Request, user database.User) error {
+	page, err := server.newPage(request, user)
+	if err != nil {
+		return err
+	}
+
+	page.Posts, err = browsePosts(state, user, browseOptions{
+		feedUrl:    page.SelectedFeed,
+		sortOrder:  "newest",
+		unreadOnly: page.UnreadOnly,
+		limit:      webPageSize,
+		page:       1,
+		after:      request.URL.Query().Get("after"),
+	})
+	if err != nil {
+		return err
+	}
+	if len(page.Posts) == webPageSize {
+		page.NextAfter = page.Posts[len(page.Posts)-1].ID.String()
+	}
+	server.render(writer, http.StatusOK, "posts", page)
+	return nil
+}
+
+// postPage shows a post. Links are prefetched by browsers, so the post is
+// marked as read by the form of the posts page, see markRead.
+func (server webServer) postPage(state *State, writer http.ResponseWriter, request *http.Request, user database.User) error {
+	post, err := findUserPathPost(state, request, user)
+	if err != nil {
+		return err
+	}
+
+	page, err := server.newPage(request, user)
+	if err != nil {
+		return err
+	}
+	page.Post = post
+	page.Content = template.HTML(server.sanitizer.Sanitize(post.Description.String))
+	server.render(writer, http.StatusOK, "post", page)
+	return nil
+}
+
+// markRead marks a post as read and opens it
+func (server webServer) markRead(state *State, writer http.ResponseWriter, request *http.Request, user database.User) error {
+	post, err := findUserPathPost(state, request, user)
+	if err != nil {
+		return err
+	}
+	if _, err := setPostRead(state, request, user, post.ID, true); err != nil {
+		return err
+	}
+	http.Redirect(writer, request, "/posts/"+post.ID.String(), http.StatusSeeOther)
+	return nil
+}
+
+func (server webServer) markUnread(state *State, writer http.ResponseWriter, request *http.Request, user database.User) error {
+	post, err := findUserPathPost(state, request, user)
+	if err != nil {
+		return err
+	}
+	if _, err := setPostRead(state, request, user, post.ID, false); err != nil {
+		return err
+	}
+	http.Redirect(writer, request, "/", http.StatusSeeOther)
+	return nil
+}
+
+func (server webServer) follow(state *State, writer http.ResponseWriter, request *http.Request, user database.User) error {
+	feedUrl := request.PostFormValue("feed_url")
+	if feedUrl == "" {
+		return validationError("Feed url is missing")
+	}
+	feed, err := state.db.GetFeed(request.Context(), feedUrl)
+	if err != nil {
+		return fmt.Errorf("The feed '%s' is not registered yet. Add it with 'addfeed' first: %w", feedUrl, err)
+	}
+
+	now := time.Now()
+	if _, err := state.db.CreateFeedFollow(request.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		FeedID:    feed.ID,
+		UserID:    user.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}); err != nil {
+		return fmt.Errorf("Failed to follow '%s': %w", feedUrl, err)
+	}
+	http.Redirect(writer, request, "/?feed="+url.QueryEscape(feedUrl), http.StatusSeeOther)
+	return nil
+}
+
+func (server webServer) unfollow(state *State, writer http.ResponseWriter, request *http.Request, user database.User) error {
+	feedUrl := request.PostFormValue("feed_url")
+	if _, err := state.db.DeleteFeedFollow(request.Context(), database.DeleteFeedFollowParams{
+		UserID:  user.ID,
+		FeedUrl: feedUrl,
+	}); err != nil {
+		return fmt.Errorf("Failed to unfollow '%s': %w", feedUrl, err)
+	}
+	http.Redirect(writer, request, "/", http.StatusSeeOther)
+	return nil
+}
+
+func (server webServer) render(writer http.ResponseWriter, status int, name string, page webPage) {
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.WriteHeader(status)
+	// The status is already sent, so errors can only be logged
+	if err := server.templates[name].ExecuteTemplate(writer, "layout", page); err != nil {
+		log.Printf("Failed to render template '%s': %v", name, err)
+	}
+}
+
+func (server webServer) renderError(writer http.ResponseWriter, status int, page webPage, err error) {
+	page.Error = err.Error()
+	server.render(writer, status, "error", page)
+}
+
+func formatPublicationDate(date sql.NullTime) string {
+	if !date.Valid {
+		return "unknown date"
+	}
+	return date.Time.Format(time.DateTime)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestIsSameOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"same origin", map[string]string{"Origin": "http://localhost:8081"}, true},
+		{"other site", map[string]string{"Origin": "https://example.com"}, false},
+		{"other port", map[string]string{"Origin": "http://localhost:9000"}, false},
+		{"opaque origin", map[string]string{"Origin": "null"}, false},
+		{"fetch metadata only", map[string]string{"Sec-Fetch-Site": "same-origin"}, true},
+		{"cross site fetch metadata", map[string]string{"Sec-Fetch-Site": "cross-site"}, false},
+		{"no headers", map[string]string{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "http://localhost:8081/follow", nil)
+			for name, value := range test.headers {
+				request.Header.Set(name, value)
+			}
+			if got := isSameOrigin(request); got != test.want {
+				t.Errorf("isSameOrigin() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidCSRFToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"token of the session", csrfToken("session"), true},
+		{"token of another session", csrfToken("other"), false},
+		{"session token", "session", false},
+		{"missing", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			form := url.Values{"csrf_token": {test.token}}
+			request := httptest.NewRequest("POST", "/follow", strings.NewReader(form.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if got := validCSRFToken(request, "session"); got != test.want {
+				t.Errorf("validCSRFToken() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}